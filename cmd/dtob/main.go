@@ -15,6 +15,7 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/binary"
 	"flag"
@@ -28,11 +29,99 @@ import (
 
 const zeros = "0000000000000000000"
 
+// digitReader skips whitespace such as trailing newlines in the input.
+type digitReader struct {
+	rd *bufio.Reader
+}
+
+func (r *digitReader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		c, err := r.rd.ReadByte()
+		if err != nil {
+			return n, err
+		}
+		if c == '\n' || c == '\r' || c == ' ' || c == '\t' {
+			continue
+		}
+		p[n] = c
+		n++
+	}
+	return n, nil
+}
+
+// writeYCD writes the digits from rd to YCD files named "<prefix> - <block ID>.ycd".
+// The last block sets TotalDigits if it's shorter than blockSize.
+func writeYCD(rd io.Reader, prefix string, radix, blockSize int, firstDigits string) error {
+	block := make([]byte, blockSize)
+	for id := int64(0); ; id++ {
+		n, err := io.ReadFull(rd, block)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return err
+		}
+		h := &ycd.Header{
+			Radix:       radix,
+			FirstDigits: firstDigits,
+			BlockSize:   int64(blockSize),
+			BlockID:     id,
+		}
+		if n < blockSize {
+			h.TotalDigits = id*int64(blockSize) + int64(n)
+		}
+		if err := writeYCDFile(fmt.Sprintf("%s - %d.ycd", prefix, id), h, block[:n]); err != nil {
+			return err
+		}
+		if n < blockSize {
+			return nil
+		}
+	}
+}
+
+func writeYCDFile(name string, h *ycd.Header, digits []byte) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(f)
+	w, err := ycd.NewWriter(bw, h)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := w.Write(digits); err != nil {
+		f.Close()
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	if err := w.Close(); err != nil {
+		f.Close()
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	if err := bw.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
 func main() {
 	radix := flag.Int("r", 10, "radix")
 	blockSize := flag.Int("b", 100, "block size")
+	ycdPrefix := flag.String("ycd", "", "write YCD files named \"<prefix> - <block ID>.ycd\" instead of printing byte literals")
+	firstDigits := flag.String("first", "", "FirstDigits header field of the YCD files (e.g. 3.14159)")
 	flag.Parse()
 
+	if *ycdPrefix != "" {
+		rd := &digitReader{rd: bufio.NewReader(os.Stdin)}
+		if err := writeYCD(rd, *ycdPrefix, *radix, *blockSize, *firstDigits); err != nil {
+			fmt.Fprintf(os.Stderr, "writeYCD: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	dpw := ycd.DigitsPerWord(*radix)
 
 	block := make([]byte, *blockSize)
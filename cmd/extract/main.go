@@ -22,7 +22,9 @@ import (
 	"os"
 
 	"github.com/googlecloudplatform/pi-delivery/gen/index"
+	"github.com/googlecloudplatform/pi-delivery/pkg/obj"
 	"github.com/googlecloudplatform/pi-delivery/pkg/obj/gcs"
+	"github.com/googlecloudplatform/pi-delivery/pkg/obj/local"
 	"github.com/googlecloudplatform/pi-delivery/pkg/unpack"
 )
 
@@ -31,6 +33,7 @@ func main() {
 	n := flag.Int64("n", 100, "Number of digits to read")
 	outfile := flag.String("o", "-", "Output file")
 	useReadAt := flag.Bool("a", false, "Use ReadAt")
+	localRoot := flag.String("local", "", "Read from a local directory containing the bucket instead of Cloud Storage")
 	flag.Parse()
 
 	if *n <= 0 {
@@ -52,7 +55,13 @@ func main() {
 	}
 
 	ctx := context.Background()
-	sc, err := gcs.NewClient(ctx)
+	var sc obj.Client
+	var err error
+	if *localRoot != "" {
+		sc, err = local.NewClient(*localRoot)
+	} else {
+		sc, err = gcs.NewClient(ctx)
+	}
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "couldn't initialize storage client: %v\n", err)
 		os.Exit(1)
@@ -20,52 +20,54 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path"
+	"path/filepath"
+	"regexp"
 	"sort"
+	"strings"
+	"unicode"
 
-	"cloud.google.com/go/storage"
+	"github.com/googlecloudplatform/pi-delivery/pkg/obj"
+	"github.com/googlecloudplatform/pi-delivery/pkg/obj/gcs"
+	"github.com/googlecloudplatform/pi-delivery/pkg/obj/local"
 	"github.com/googlecloudplatform/pi-delivery/pkg/resultset"
 	"github.com/googlecloudplatform/pi-delivery/pkg/ycd"
 	"go.uber.org/zap"
-	"google.golang.org/api/iterator"
-	"google.golang.org/api/option"
 )
 
 // All the YCD files I tested are smaller than 256 bytes so let's just fetch the first
 // one kilobyte. This program fails if a header is somehow bigger than this limit.
 const maxHeaderLength = 1024
 
+// ycdExt is the file extension of YCD files. Other objects, e.g. stats, are skipped.
+const ycdExt = ".ycd"
+
 var logger *zap.SugaredLogger
 
-var bucketName = flag.String("bucket", "", "bucket name (e.g. pi-delivery-public)")
+var bucketName = flag.String("bucket", "", "bucket name (e.g. pi-delivery-public). With a directory argument, it defaults to the directory name")
 var hexPrefix = flag.String("hex", "Pi - Hex - Chudnovsky", "prefix for hexadecimal results")
 var decPrefix = flag.String("dec", "Pi - Dec - Chudnovsky", "prefix for decimal results")
-var prefix = flag.String("prefix", "", "common prefix for the result objects")
+var prefix = flag.String("prefix", "", "common prefix for the result objects. With a directory argument, it's prepended to the object names")
+var discover = flag.Bool("discover", false, "discover results of all the constants under --prefix instead of using --dec and --hex")
+var manifestFile = flag.String("manifest", "", "file to write a JSON manifest of the result sets to (optional)")
 
-func listObjects(ctx context.Context, bucket *storage.BucketHandle, prefix string) ([]string, error) {
+func listObjects(ctx context.Context, bucket obj.Bucket, prefix string) ([]string, error) {
 	logger.Infow("listObjects",
 		"prefix", prefix,
 	)
 
-	query := &storage.Query{Prefix: prefix}
-	query.SetAttrSelection([]string{"Name"})
-	iter := bucket.Objects(ctx, query)
-	objects := []string{}
-	for {
-		attrs, err := iter.Next()
-		if err == iterator.Done {
-			break
-		}
-		if err != nil {
-			logger.Errorw("failed to list objects",
-				"prefix", prefix,
-				"error", err,
-			)
-			return nil, err
-		}
+	objects, err := obj.ListNames(ctx, bucket, &obj.Query{Prefix: prefix})
+	if err != nil {
+		logger.Errorw("failed to list objects",
+			"prefix", prefix,
+			"error", err,
+		)
+		return nil, err
+	}
+	for _, name := range objects {
 		logger.Infow("object found",
-			"name", attrs.Name,
+			"name", name,
 		)
-		objects = append(objects, attrs.Name)
 	}
 	logger.Infow("listObjects finished",
 		"prefix", prefix,
@@ -74,14 +76,105 @@ func listObjects(ctx context.Context, bucket *storage.BucketHandle, prefix strin
 	return objects, nil
 }
 
-func newStorageClient(ctx context.Context) *storage.Client {
-	option.WithScopes("ScopeReadOnly")
-	client, err := storage.NewClient(ctx)
+// resultDirPattern matches the directory names y-cruncher creates,
+// e.g. "Pi - Dec - Chudnovsky" or "e - Hex - Binary Splitting".
+var resultDirPattern = regexp.MustCompile(`^(.+) - (Dec|Hex) - (.+)$`)
+
+// resultDir is a directory of YCD files for a constant in a radix.
+type resultDir struct {
+	prefix   string
+	constant string
+	radix    int
+}
+
+// listResultDirs lists the directories directly under prefix that match
+// resultDirPattern.
+func listResultDirs(ctx context.Context, bucket obj.Bucket, prefix string) ([]resultDir, error) {
+	logger.Infow("listResultDirs",
+		"prefix", prefix,
+	)
+
+	iter := bucket.Objects(ctx, &obj.Query{Prefix: prefix, Delimiter: "/"})
+	dirs := []resultDir{}
+	for {
+		entry, err := iter.Next()
+		if err == obj.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if entry.Prefix == "" {
+			continue
+		}
+		name := strings.TrimSuffix(strings.TrimPrefix(entry.Prefix, prefix), "/")
+		m := resultDirPattern.FindStringSubmatch(name)
+		if m == nil {
+			logger.Infow("skipping directory",
+				"name", name,
+			)
+			continue
+		}
+		radix := 10
+		if m[2] == "Hex" {
+			radix = 16
+		}
+		logger.Infow("result directory found",
+			"name", name,
+			"constant", m[1],
+			"radix", radix,
+		)
+		dirs = append(dirs, resultDir{
+			prefix:   strings.TrimSuffix(entry.Prefix, "/"),
+			constant: m[1],
+			radix:    radix,
+		})
+	}
+	sort.Slice(dirs, func(i, j int) bool { return dirs[i].prefix < dirs[j].prefix })
+	return dirs, nil
+}
+
+// varName returns the Go variable name for the result set of constant in radix.
+// Pi keeps the names Decimal and Hexadecimal for compatibility.
+// Other constants are prefixed with the constant name, e.g. GoldenRatioDecimal.
+func varName(constant string, radix int) string {
+	suffix := "Decimal"
+	if radix == 16 {
+		suffix = "Hexadecimal"
+	}
+	if resultset.NormalizeConstant(constant) == resultset.Pi {
+		return suffix
+	}
+	var b strings.Builder
+	upper := true
+	for _, r := range constant {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String() + suffix
+}
+
+// newClient returns a client for the local directory dir if it's set,
+// or for Cloud Storage otherwise.
+func newClient(ctx context.Context, dir string) obj.Client {
+	var client obj.Client
+	var err error
+	if dir != "" {
+		client, err = local.NewClient(dir)
+	} else {
+		client, err = gcs.NewClient(ctx)
+	}
 	if err != nil {
-		logger.Fatalw("failed to create a Storage client",
+		logger.Fatalw("failed to create a storage client",
 			"error", err,
 		)
-		os.Exit(1)
 	}
 	return client
 }
@@ -100,45 +193,51 @@ func logYCDInfo(file *ycd.YCDFile) {
 	)
 }
 
-func fetchYCDFiles(ctx context.Context, client *storage.Client, bucketName, prefix string) resultset.ResultSet {
-	bucket := client.Bucket(bucketName)
+func fetchYCDFiles(ctx context.Context, bucket obj.Bucket, bucketName, prefix string) resultset.ResultSet {
 	objects, err := listObjects(ctx, bucket, prefix)
 	if err != nil {
 		logger.Fatalw("failed to list objects for decimal results",
 			"error", err,
 			"prefix", prefix,
 		)
-		os.Exit(1)
 	}
+	return parseYCDFiles(ctx, bucket, bucketName, objects)
+}
+
+// parseYCDFiles parses the headers of the YCD files among objects in bucket
+// and returns them sorted by BlockID.
+func parseYCDFiles(ctx context.Context, bucket obj.Bucket, bucketName string, objects []string) resultset.ResultSet {
 	files := resultset.ResultSet{}
 	for _, name := range objects {
-		object := bucket.Object(name)
-		reader, err := object.NewRangeReader(ctx, 0, maxHeaderLength)
-		if err != nil {
-			logger.Fatalw("creating an object reader failed",
-				"error", err,
-				"bucket", bucketName,
-				"object", name,
-			)
-			os.Exit(1)
+		if !strings.HasSuffix(name, ycdExt) {
+			continue
 		}
-		defer reader.Close()
-		ycd, err := ycd.Parse(reader)
+		file, err := fetchYCDFile(ctx, bucket.Object(name))
 		if err != nil {
-			logger.Errorw("failed to parse a ycd file",
+			logger.Fatalw("failed to read a ycd file",
 				"error", err,
 				"bucket", bucketName,
-				"object", object,
+				"object", name,
 			)
 		}
-		ycd.Name = name
-		logYCDInfo(ycd)
-		files = append(files, ycd)
+		file.Name = name
+		logYCDInfo(file)
+		files = append(files, file)
 	}
 	sort.Sort(files)
 	return files
 }
 
+// fetchYCDFile parses the header of the YCD file in object.
+func fetchYCDFile(ctx context.Context, object obj.Object) (*ycd.YCDFile, error) {
+	reader, err := object.NewRangeReader(ctx, 0, maxHeaderLength)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return ycd.Parse(reader)
+}
+
 func printIndexPrologue(w io.Writer, bucketName string) {
 	fmt.Fprintln(w, `// Code generated by indexer. DO NOT EDIT.
 // Run indexer/main.go to generate this file.
@@ -161,11 +260,11 @@ func printIndexFileList(w io.Writer, varName string, files resultset.ResultSet)
 		Header: &ycd.Header{
 			FileVersion: "%s",
 			Radix: %d,
-			FirstDigits: "%s",
+			FirstDigits: %q,
 			TotalDigits: int64(%d),
 			BlockSize: int64(%d),
 			BlockID: int64(%d),
-			Length: %d,
+			Length: %d,%s
 		},
 		Name: "%s",
 		FirstDigitOffset: %d,
@@ -177,6 +276,7 @@ func printIndexFileList(w io.Writer, varName string, files resultset.ResultSet)
 			v.Header.BlockSize,
 			v.Header.BlockID,
 			v.Header.Length,
+			extraField(v.Header.Extra),
 			v.Name,
 			v.FirstDigitOffset,
 		)
@@ -186,9 +286,117 @@ func printIndexFileList(w io.Writer, varName string, files resultset.ResultSet)
 	fmt.Fprintln(w)
 }
 
-func processDirectory(ctx context.Context, client *storage.Client, w io.Writer, varName, bucketName, prefix string) {
-	files := fetchYCDFiles(ctx, client, bucketName, prefix)
+// extraField returns the Extra field of a generated ycd.Header literal,
+// or an empty string if there are no extra header keys.
+func extraField(extra map[string]string) string {
+	if len(extra) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("\n\t\t\tExtra: %#v,", extra)
+}
+
+func processDirectory(ctx context.Context, bucket obj.Bucket, w io.Writer, varName, bucketName, prefix string) resultset.ResultSet {
+	files := fetchYCDFiles(ctx, bucket, bucketName, prefix)
+	if err := files.Validate(); err != nil {
+		logger.Fatalw("invalid result set",
+			"error", err,
+			"prefix", prefix,
+		)
+	}
 	printIndexFileList(w, varName, files)
+	return files
+}
+
+// bucketNamePattern matches valid Cloud Storage bucket names.
+var bucketNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{1,220}[a-z0-9]$`)
+
+// processLocalDirectory indexes every YCD file under the local directory that
+// bucket reads. Files are grouped into result sets by their directories and the
+// constant is taken from the directory name. Files directly in the directory are
+// named under dirName as they would be after copying the directory to the bucket.
+// namePrefix is prepended to all the names.
+func processLocalDirectory(ctx context.Context, bucket obj.Bucket, w io.Writer, m *resultset.Manifest, dirName, namePrefix string) {
+	objects, err := listObjects(ctx, bucket, "")
+	if err != nil {
+		logger.Fatalw("failed to list the directory",
+			"error", err,
+		)
+	}
+	groups := map[string][]string{}
+	for _, name := range objects {
+		if strings.HasSuffix(name, ycdExt) {
+			groups[path.Dir(name)] = append(groups[path.Dir(name)], name)
+		}
+	}
+	if len(groups) == 0 {
+		logger.Fatalw("no YCD files found",
+			"dir", dirName,
+		)
+	}
+	dirs := make([]string, 0, len(groups))
+	for dir := range groups {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	for _, dir := range dirs {
+		files := parseYCDFiles(ctx, bucket, m.BucketName, groups[dir])
+		if err := files.Validate(); err != nil {
+			logger.Fatalw("invalid result set",
+				"error", err,
+				"dir", dir,
+			)
+		}
+		resultDir := dir
+		if dir == "." {
+			resultDir = dirName
+			for _, f := range files {
+				f.Name = dirName + "/" + f.Name
+			}
+		}
+		for _, f := range files {
+			f.Name = namePrefix + f.Name
+		}
+		constant := resultset.Pi
+		if match := resultDirPattern.FindStringSubmatch(path.Base(resultDir)); match != nil {
+			constant = match[1]
+		}
+		name := varName(constant, files.Radix())
+		if _, ok := m.ResultSets[name]; ok {
+			logger.Warnw("skipping a duplicate result directory",
+				"dir", resultDir,
+				"name", name,
+			)
+			continue
+		}
+		printIndexFileList(w, name, files)
+		m.ResultSets[name] = files
+		m.Constants[name] = resultset.NormalizeConstant(constant)
+	}
+}
+
+func writeManifest(path string, m *resultset.Manifest) {
+	f, err := os.Create(path)
+	if err != nil {
+		logger.Fatalw("failed to create the manifest file",
+			"error", err,
+			"path", path,
+		)
+	}
+	if err := resultset.WriteManifest(f, m); err != nil {
+		logger.Fatalw("failed to write the manifest",
+			"error", err,
+			"path", path,
+		)
+	}
+	if err := f.Close(); err != nil {
+		logger.Fatalw("failed to close the manifest file",
+			"error", err,
+			"path", path,
+		)
+	}
+	logger.Infow("manifest written",
+		"path", path,
+	)
 }
 
 func main() {
@@ -201,21 +409,80 @@ func main() {
 	}
 	flag.Parse()
 
+	// A directory argument is indexed instead of the bucket.
+	dir := ""
+	switch flag.NArg() {
+	case 0:
+	case 1:
+		var err error
+		if dir, err = filepath.Abs(flag.Arg(0)); err != nil {
+			logger.Fatalw("invalid directory",
+				"error", err,
+				"dir", flag.Arg(0),
+			)
+		}
+		if *bucketName == "" {
+			*bucketName = filepath.Base(dir)
+			if !bucketNamePattern.MatchString(*bucketName) {
+				logger.Errorf("can't use the directory name %q as the bucket name, set it with --bucket", *bucketName)
+				os.Exit(1)
+			}
+		}
+	default:
+		logger.Errorf("only one directory can be indexed at a time")
+		os.Exit(1)
+	}
 	if *bucketName == "" {
-		logger.Errorf("bucket name is required (--bucket)")
+		logger.Errorf("bucket name (--bucket) or directory is required")
 		os.Exit(1)
 	}
 
 	ctx := context.Background()
 
-	client := newStorageClient(ctx)
+	client := newClient(ctx, dir)
 	defer func() {
 		if err := client.Close(); err != nil {
-			logger.Errorw("failed to close the Storage client",
+			logger.Errorw("failed to close the storage client",
 				"error", err)
 		}
 	}()
+	bucket := client.Bucket(*bucketName)
+	if dir != "" {
+		bucket = client.Bucket("")
+	}
+	manifest := &resultset.Manifest{
+		BucketName: *bucketName,
+		ResultSets: map[string]resultset.ResultSet{},
+		Constants:  map[string]string{},
+	}
 	printIndexPrologue(os.Stdout, *bucketName)
-	processDirectory(ctx, client, os.Stdout, "Decimal", *bucketName, *prefix+*decPrefix)
-	processDirectory(ctx, client, os.Stdout, "Hexadecimal", *bucketName, *prefix+*hexPrefix)
+	if dir != "" {
+		processLocalDirectory(ctx, bucket, os.Stdout, manifest, filepath.Base(dir), *prefix)
+	} else if *discover {
+		dirs, err := listResultDirs(ctx, bucket, *prefix)
+		if err != nil {
+			logger.Fatalw("failed to list result directories",
+				"error", err,
+				"prefix", *prefix,
+			)
+		}
+		for _, dir := range dirs {
+			name := varName(dir.constant, dir.radix)
+			if _, ok := manifest.ResultSets[name]; ok {
+				logger.Warnw("skipping a duplicate result directory",
+					"prefix", dir.prefix,
+					"name", name,
+				)
+				continue
+			}
+			manifest.ResultSets[name] = processDirectory(ctx, bucket, os.Stdout, name, *bucketName, dir.prefix+"/")
+			manifest.Constants[name] = resultset.NormalizeConstant(dir.constant)
+		}
+	} else {
+		manifest.ResultSets["Decimal"] = processDirectory(ctx, bucket, os.Stdout, "Decimal", *bucketName, *prefix+*decPrefix)
+		manifest.ResultSets["Hexadecimal"] = processDirectory(ctx, bucket, os.Stdout, "Hexadecimal", *bucketName, *prefix+*hexPrefix)
+	}
+	if *manifestFile != "" {
+		writeManifest(*manifestFile, manifest)
+	}
 }
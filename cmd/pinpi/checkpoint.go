@@ -0,0 +1,188 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/goccy/go-json"
+)
+
+// A checkpoint file is a JSON lines file. The first line is a checkpointHeader
+// and each following line is a checkpointEntry for a completed task.
+// Entries are appended after the results of the task are written to the output
+// and record the size of the output at that point. On resume, the output is
+// truncated to the last recorded size so results written after the last entry
+// are discarded and written again when the task is redone.
+
+// checkpointHeader is the configuration of the scan.
+// A scan can only be resumed with the same configuration.
+type checkpointHeader struct {
+	// Pattern is the pattern, or the patterns separated by spaces with -patterns-file.
+	Pattern   string `json:"pattern"`
+	Radix     int    `json:"radix"`
+	MinMatch  int    `json:"minMatch"`
+	Start     int64  `json:"start"`
+	End       int64  `json:"end"`
+	ChunkSize int64  `json:"chunkSize"`
+	Format    string `json:"format"`
+}
+
+// checkpointEntry records a completed task.
+type checkpointEntry struct {
+	Start      int64    `json:"start"`
+	N          int64    `json:"n"`
+	OutputSize int64    `json:"outputSize"`
+	Results    []result `json:"results"`
+}
+
+type checkpoint struct {
+	mu         sync.Mutex
+	f          *os.File
+	done       map[int64]int64
+	outputSize int64
+}
+
+// createCheckpoint creates a new checkpoint file at path, overwriting an existing one.
+func createCheckpoint(path string, h *checkpointHeader) (*checkpoint, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, err
+	}
+	c := &checkpoint{
+		f:    f,
+		done: make(map[int64]int64),
+	}
+	if err := c.append(h); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// resumeCheckpoint opens the checkpoint file at path and reads the completed tasks.
+// It returns an error if the file was created with a different configuration.
+// A partially written last line is discarded.
+func resumeCheckpoint(path string, h *checkpointHeader) (*checkpoint, error) {
+	f, err := os.OpenFile(path, os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	c := &checkpoint{
+		f:    f,
+		done: make(map[int64]int64),
+	}
+	if err := c.load(h); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return c, nil
+}
+
+func (c *checkpoint) load(h *checkpointHeader) error {
+	r := bufio.NewReader(c.f)
+	valid := int64(0)
+	for lineNo := 0; ; lineNo++ {
+		line, err := r.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			// Either the end of the file or a partially written line.
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if lineNo == 0 {
+			var got checkpointHeader
+			if err := json.Unmarshal(line, &got); err != nil {
+				return fmt.Errorf("invalid header: %w", err)
+			}
+			if got != *h {
+				return fmt.Errorf("configuration mismatch: checkpoint %+v, flags %+v", got, *h)
+			}
+		} else {
+			var e checkpointEntry
+			if err := json.Unmarshal(line, &e); err != nil {
+				return fmt.Errorf("invalid entry at line %d: %w", lineNo+1, err)
+			}
+			c.done[e.Start] = e.N
+			c.outputSize = e.OutputSize
+		}
+		valid += int64(len(line))
+	}
+	if valid == 0 {
+		return errors.New("missing header")
+	}
+	if err := c.f.Truncate(valid); err != nil {
+		return err
+	}
+	_, err := c.f.Seek(valid, io.SeekStart)
+	return err
+}
+
+// completed returns true if the task [start, start+n) is already done.
+func (c *checkpoint) completed(start, n int64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	done, ok := c.done[start]
+	return ok && done == n
+}
+
+// record appends e to the checkpoint file and syncs it.
+func (c *checkpoint) record(e *checkpointEntry) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.append(e); err != nil {
+		return err
+	}
+	c.done[e.Start] = e.N
+	c.outputSize = e.OutputSize
+	return nil
+}
+
+func (c *checkpoint) append(v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if bytes.IndexByte(b, '\n') >= 0 {
+		return errors.New("checkpoint entry contains a newline")
+	}
+	if _, err := c.f.Write(append(b, '\n')); err != nil {
+		return err
+	}
+	return c.f.Sync()
+}
+
+func (c *checkpoint) Close() error {
+	return c.f.Close()
+}
+
+// countingWriter counts the bytes written to an output file.
+type countingWriter struct {
+	f *os.File
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.f.Write(p)
+	w.n += int64(n)
+	return n, err
+}
@@ -15,30 +15,32 @@
 package main
 
 import (
-	"bytes"
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"os"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/googlecloudplatform/pi-delivery/gen/index"
 	"github.com/googlecloudplatform/pi-delivery/pkg/obj"
 	"github.com/googlecloudplatform/pi-delivery/pkg/obj/gcs"
+	"github.com/googlecloudplatform/pi-delivery/pkg/obj/local"
+	"github.com/googlecloudplatform/pi-delivery/pkg/resultset"
+	"github.com/googlecloudplatform/pi-delivery/pkg/search"
 	"github.com/googlecloudplatform/pi-delivery/pkg/unpack"
 	"github.com/sethvargo/go-retry"
 	"go.uber.org/zap"
 )
 
 const (
-	TOTAL_NUMBERS     = 1_000_000_00
-	DIGITS_PER_NUMBER = 8
-	CHUNK_SIZE        = 100_000_000
-	WORKERS           = 256
-	SEQUENCE          = "3141592653589793238462643383279502884197169399375105820974944592307816406286208998628034825342117067"
-	MIN_MATCH         = 10
+	DEFAULT_CHUNK_SIZE = 100_000_000
+	DEFAULT_WORKERS    = 256
+	DEFAULT_SEQUENCE   = "3141592653589793238462643383279502884197169399375105820974944592307816406286208998628034825342117067"
+	DEFAULT_MIN_MATCH  = 10
 )
 
 var logger *zap.SugaredLogger
@@ -52,54 +54,88 @@ type task struct {
 	cancel context.CancelFunc
 }
 
-func process(ctx context.Context, task *task, logger *zap.SugaredLogger, client obj.Client) error {
+// searcher holds the search configuration shared by the workers.
+type searcher struct {
+	client   obj.Client
+	set      resultset.ResultSet
+	pattern  []byte
+	minMatch int
+	// matcher is set instead of pattern to search for multiple patterns.
+	matcher *search.Matcher
+	// overlap is the number of digits read past the end of a task.
+	overlap int
+	out     output
+
+	// mu serializes writes to the output and the checkpoint.
+	mu sync.Mutex
+	// outFile is the output file, or nil when writing to stdout.
+	outFile *countingWriter
+	// ckpt is nil unless -checkpoint is set.
+	ckpt *checkpoint
+}
+
+// process searches the digits [task.start, task.start+task.n) and returns the matches.
+// It reads s.overlap more digits past the end of the task so that matches
+// starting in this task and ending in the next one are found.
+func (s *searcher) process(ctx context.Context, task *task, logger *zap.SugaredLogger) ([]result, error) {
 	logger.Infof("processing task, start = %d, n = %v", task.start, task.n)
 
-	rrd := index.Decimal.NewReader(ctx, client.Bucket(index.BucketName))
+	rrd := s.set.NewReader(ctx, s.client.Bucket(index.BucketName))
 	defer rrd.Close()
 	urd := unpack.NewReader(ctx, rrd)
 	if _, err := urd.Seek(task.start, io.SeekStart); err != nil {
-		return err
+		return nil, err
+	}
+	if s.matcher != nil {
+		results, err := s.findAll(ctx, io.LimitReader(urd, task.n+int64(s.overlap)), task)
+		if err != nil {
+			return nil, err
+		}
+		logger.Infof("digits processed: %d + %d digits", task.start, task.n)
+		return results, nil
 	}
-	buf := make([]byte, task.n)
+	buf := make([]byte, task.n+int64(s.overlap))
 	n, err := io.ReadFull(urd, buf)
-	if n < MIN_MATCH {
-		return err
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, err
 	}
 	buf = buf[:n]
-	off := 0
-	for len(buf) >= MIN_MATCH {
-		i := bytes.Index(buf, []byte(SEQUENCE[:MIN_MATCH]))
-		if i < 0 {
-			break
-		}
-		match := buf[i:]
-		if len(match) < len(SEQUENCE) {
-			p := make([]byte, len(SEQUENCE)-len(match))
-			if n, err := io.ReadFull(urd, p); n == 0 {
-				return err
-			}
-			match = append(match, p...)
-		}
-		l := 0
-		for ; l < len(SEQUENCE)-MIN_MATCH; l++ {
-			if match[MIN_MATCH+l] != SEQUENCE[MIN_MATCH+l] {
-				break
-			}
-		}
-		matchLen := MIN_MATCH + l
-		fmt.Printf("%v, %v, %s\n",
-			task.start+int64(off+i+1), matchLen, string(match[:matchLen]))
-		buf = buf[i+matchLen:]
-		off += i + matchLen
-	}
+
+	results := make([]result, 0)
+	search.PrefixMatches(buf, int(task.n), s.pattern, s.minMatch, func(pos, length int) {
+		results = append(results, result{
+			Position: task.start + int64(pos) + 1,
+			Length:   length,
+			Digits:   string(buf[pos : pos+length]),
+		})
+	})
 
 	logger.Infof("digits processed: %d + %d digits",
 		task.start, task.n)
-	return nil
+	return results, nil
+}
+
+// findAll returns the occurrences of the patterns of s.matcher that start in the task
+// in a single pass over rd, which reads the digits from task.start.
+func (s *searcher) findAll(ctx context.Context, rd io.Reader, task *task) ([]result, error) {
+	results := make([]result, 0)
+	end := task.start + task.n
+	err := search.FindMulti(ctx, rd, task.start, s.matcher, false, func(m search.MultiMatch) bool {
+		// Matches starting past the end are found by the next task.
+		if m.Offset < end {
+			p := s.matcher.Pattern(m.Pattern)
+			results = append(results, result{
+				Position: m.Offset + 1,
+				Length:   len(p),
+				Digits:   string(p),
+			})
+		}
+		return true
+	})
+	return results, err
 }
 
-func worker(ctx context.Context, taskChan <-chan task, client obj.Client) {
+func (s *searcher) worker(ctx context.Context, taskChan <-chan task) {
 	defer wg.Done()
 	logger := logger.With("worker id", ctx.Value(workerContextKey("workerId")))
 	defer logger.Sync()
@@ -114,17 +150,119 @@ func worker(ctx context.Context, taskChan <-chan task, client obj.Client) {
 		default:
 		}
 
+		// Results are written only after the task succeeds
+		// so retries don't produce duplicates.
+		var results []result
 		if err := retry.Do(ctx, b, func(ctx context.Context) error {
-			if err := process(ctx, &task, logger, client); err != nil {
+			var err error
+			if results, err = s.process(ctx, &task, logger); err != nil {
 				return retry.RetryableError(err)
 			}
 			return nil
 		}); err != nil {
 			logger.Errorw("process failed", "error", err)
 			task.cancel()
+			continue
+		}
+		if err := s.commit(&task, results); err != nil {
+			logger.Errorw("write failed", "error", err)
+			task.cancel()
+		}
+	}
+}
+
+// commit writes the results of a completed task to the output and then
+// records the task in the checkpoint.
+func (s *searcher) commit(task *task, results []result) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.out.write(results); err != nil {
+		return err
+	}
+	if s.ckpt == nil {
+		return nil
+	}
+	if err := s.outFile.f.Sync(); err != nil {
+		return err
+	}
+	return s.ckpt.record(&checkpointEntry{
+		Start:      task.start,
+		N:          task.n,
+		OutputSize: s.outFile.n,
+		Results:    results,
+	})
+}
+
+// openOutput opens the output file. On resume, it truncates the file to the
+// size recorded in the checkpoint to drop results of unfinished tasks.
+func openOutput(path string, ckpt *checkpoint, resume bool) (*countingWriter, error) {
+	if !resume {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+		if err != nil {
+			return nil, err
 		}
+		return &countingWriter{f: f}, nil
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
 	}
+	if fi.Size() < ckpt.outputSize {
+		f.Close()
+		return nil, fmt.Errorf("%s is shorter than recorded in the checkpoint: %d < %d", path, fi.Size(), ckpt.outputSize)
+	}
+	if err := f.Truncate(ckpt.outputSize); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if _, err := f.Seek(ckpt.outputSize, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &countingWriter{f: f, n: ckpt.outputSize}, nil
+}
 
+// readPattern returns the pattern from the flags.
+// A pattern file may wrap the digits over multiple lines.
+func readPattern(pattern, patternFile string, radix int) ([]byte, error) {
+	if patternFile != "" {
+		b, err := os.ReadFile(patternFile)
+		if err != nil {
+			return nil, err
+		}
+		pattern = strings.Join(strings.Fields(string(b)), "")
+	}
+	p := []byte(strings.ToLower(pattern))
+	if err := search.ValidatePattern(p, radix); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// readPatterns returns the patterns in a file for -patterns-file.
+// Patterns are separated by whitespace, e.g. one per line.
+func readPatterns(path string, radix int) ([][]byte, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var patterns [][]byte
+	for i, f := range strings.Fields(string(b)) {
+		p := []byte(strings.ToLower(f))
+		if err := search.ValidatePattern(p, radix); err != nil {
+			return nil, fmt.Errorf("pattern %d: %w", i+1, err)
+		}
+		patterns = append(patterns, p)
+	}
+	if len(patterns) == 0 {
+		return nil, fmt.Errorf("no patterns in %s", path)
+	}
+	return patterns, nil
 }
 
 func main() {
@@ -133,32 +271,187 @@ func main() {
 	zap.ReplaceGlobals(l)
 	logger = l.Sugar()
 
-	start := flag.Int64("s", 0, "Start offset")
+	pattern := flag.String("pattern", DEFAULT_SEQUENCE, "Digits to search for")
+	patternFile := flag.String("pattern-file", "", "Read the digits to search for from a file instead of -pattern")
+	patternsFile := flag.String("patterns-file", "", "Search for all the whitespace separated patterns in this file at once instead of -pattern")
+	radix := flag.Int("radix", 10, "Radix of the digits to search, 10 or 16")
+	start := flag.Int64("s", 0, "Start offset, counting the first digit after the decimal point as 0")
+	n := flag.Int64("n", -1, "Number of digits to search, or -1 to search until the end")
+	minMatch := flag.Int("min", DEFAULT_MIN_MATCH, "Minimum number of leading digits of the pattern to match, or 0 to match the whole pattern")
+	workers := flag.Int("workers", DEFAULT_WORKERS, "Number of workers")
+	chunkSize := flag.Int64("chunk", DEFAULT_CHUNK_SIZE, "Number of digits each task searches")
+	format := flag.String("format", "csv", "Output format, csv or jsonl")
+	outfile := flag.String("o", "-", "Output file")
+	localRoot := flag.String("local", "", "Read from a local directory containing the bucket instead of Cloud Storage")
+	checkpointFile := flag.String("checkpoint", "", "Record completed tasks to this file. Requires -o")
+	resume := flag.Bool("resume", false, "Resume the scan recorded in -checkpoint, skipping completed tasks")
 	flag.Parse()
 
+	var set resultset.ResultSet
+	switch *radix {
+	case 10:
+		set = index.Decimal
+	case 16:
+		set = index.Hexadecimal
+	default:
+		logger.Errorf("radix must be either 10 or 16: %d", *radix)
+		os.Exit(1)
+	}
+	var p []byte
+	var matcher *search.Matcher
+	var patternDesc string
+	overlap := 0
+	if *patternsFile != "" {
+		if *patternFile != "" {
+			logger.Errorf("-pattern-file and -patterns-file are exclusive")
+			os.Exit(1)
+		}
+		patterns, err := readPatterns(*patternsFile, *radix)
+		if err != nil {
+			logger.Errorf("invalid patterns: %v", err)
+			os.Exit(1)
+		}
+		if matcher, err = search.NewMatcher(patterns); err != nil {
+			logger.Errorf("invalid patterns: %v", err)
+			os.Exit(1)
+		}
+		descs := make([]string, len(patterns))
+		for i, v := range patterns {
+			descs[i] = string(v)
+			if len(v)-1 > overlap {
+				overlap = len(v) - 1
+			}
+		}
+		patternDesc = strings.Join(descs, " ")
+		// Only whole patterns are matched.
+		*minMatch = 0
+	} else {
+		var err error
+		if p, err = readPattern(*pattern, *patternFile, *radix); err != nil {
+			logger.Errorf("invalid pattern: %v", err)
+			os.Exit(1)
+		}
+		patternDesc = string(p)
+		overlap = len(p) - 1
+	}
+	if *workers <= 0 || *chunkSize <= 0 {
+		logger.Errorf("workers and chunk must be positive")
+		os.Exit(1)
+	}
+	if *start < 0 || *start > set.TotalDigits() {
+		logger.Errorf("start out of range: %d", *start)
+		os.Exit(1)
+	}
+	end := set.TotalDigits()
+	if *n >= 0 && *start+*n < end {
+		end = *start + *n
+	}
+
+	if *format != "csv" && *format != "jsonl" {
+		logger.Errorf("unknown output format: %s", *format)
+		os.Exit(1)
+	}
+	if *checkpointFile != "" && *outfile == "-" {
+		logger.Errorf("-checkpoint requires -o")
+		os.Exit(1)
+	}
+	if *resume && *checkpointFile == "" {
+		logger.Errorf("-resume requires -checkpoint")
+		os.Exit(1)
+	}
+
+	var ckpt *checkpoint
+	var err error
+	if *checkpointFile != "" {
+		h := &checkpointHeader{
+			Pattern:   patternDesc,
+			Radix:     *radix,
+			MinMatch:  *minMatch,
+			Start:     *start,
+			End:       end,
+			ChunkSize: *chunkSize,
+			Format:    *format,
+		}
+		if *resume {
+			ckpt, err = resumeCheckpoint(*checkpointFile, h)
+		} else {
+			ckpt, err = createCheckpoint(*checkpointFile, h)
+		}
+		if err != nil {
+			logger.Errorf("couldn't open the checkpoint: %v", err)
+			os.Exit(1)
+		}
+		defer ckpt.Close()
+	}
+
+	var w io.Writer = os.Stdout
+	var outFile *countingWriter
+	if *outfile != "-" {
+		outFile, err = openOutput(*outfile, ckpt, *resume)
+		if err != nil {
+			logger.Errorf("couldn't open %s: %v", *outfile, err)
+			os.Exit(1)
+		}
+		defer outFile.f.Close()
+		w = outFile
+	}
+	out, err := newOutput(*format, w)
+	if err != nil {
+		logger.Error(err)
+		os.Exit(1)
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
-	client, err := gcs.NewClient(ctx)
+	defer cancel()
+	var client obj.Client
+	if *localRoot != "" {
+		client, err = local.NewClient(*localRoot)
+	} else {
+		client, err = gcs.NewClient(ctx)
+	}
 	if err != nil {
-		logger.Errorf("couldn't create a GCS client: %v", err)
+		logger.Errorf("couldn't create a storage client: %v", err)
 		os.Exit(1)
 	}
 	defer client.Close()
 
+	s := &searcher{
+		client:   client,
+		set:      set,
+		pattern:  p,
+		minMatch: *minMatch,
+		matcher:  matcher,
+		overlap:  overlap,
+		out:      out,
+		outFile:  outFile,
+		ckpt:     ckpt,
+	}
 	taskChan := make(chan task, 256)
 
-	for i := 0; i < WORKERS; i++ {
+	for i := 0; i < *workers; i++ {
 		wg.Add(1)
 		ctx = context.WithValue(ctx, workerContextKey("workerId"), i)
-		go worker(ctx, taskChan, client)
+		go s.worker(ctx, taskChan)
 	}
 
-	for i := *start; i < index.Decimal.TotalDigits(); i += CHUNK_SIZE {
+	for i := *start; i < end; i += *chunkSize {
 		task := task{
 			start:  i,
-			n:      CHUNK_SIZE,
+			n:      *chunkSize,
 			cancel: cancel,
 		}
-		taskChan <- task
+		if i+task.n > end {
+			task.n = end - i
+		}
+		if ckpt != nil && ckpt.completed(task.start, task.n) {
+			logger.Infof("skipping completed task, start = %d, n = %d", task.start, task.n)
+			continue
+		}
+		// Workers stop taking tasks once one of them fails.
+		select {
+		case taskChan <- task:
+		case <-ctx.Done():
+		}
 		if ctx.Err() != nil {
 			logger.Errorf("context error: %v", ctx.Err())
 			break
@@ -166,4 +459,7 @@ func main() {
 	}
 	close(taskChan)
 	wg.Wait()
+	if ctx.Err() != nil {
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,88 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+
+	"github.com/goccy/go-json"
+)
+
+// result is a match found by pinpi.
+type result struct {
+	// Position counts the first digit before the decimal point as 0
+	// as in the REST API.
+	Position int64  `json:"position"`
+	Length   int    `json:"length"`
+	Digits   string `json:"digits"`
+}
+
+// output writes results. It's safe for concurrent use.
+type output interface {
+	write(results []result) error
+}
+
+func newOutput(format string, w io.Writer) (output, error) {
+	switch format {
+	case "csv":
+		return &csvOutput{w: csv.NewWriter(w)}, nil
+	case "jsonl":
+		return &jsonlOutput{enc: json.NewEncoder(w)}, nil
+	}
+	return nil, fmt.Errorf("unknown output format: %s", format)
+}
+
+// csvOutput writes results as CSV records of position, length and digits.
+type csvOutput struct {
+	mu sync.Mutex
+	w  *csv.Writer
+}
+
+func (o *csvOutput) write(results []result) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for _, r := range results {
+		if err := o.w.Write([]string{
+			strconv.FormatInt(r.Position, 10),
+			strconv.Itoa(r.Length),
+			r.Digits,
+		}); err != nil {
+			return err
+		}
+	}
+	o.w.Flush()
+	return o.w.Error()
+}
+
+// jsonlOutput writes results as JSON objects, one per line.
+type jsonlOutput struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func (o *jsonlOutput) write(results []result) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for i := range results {
+		if err := o.enc.Encode(&results[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -36,6 +36,18 @@ func main() {
 	if err := funcframework.RegisterHTTPFunctionContext(ctx, "/", server.Get); err != nil {
 		l.Sugar().Fatalf("funcframework.RegisterHTTPFunctionContext: %v\n", err)
 	}
+	if err := funcframework.RegisterHTTPFunctionContext(ctx, "/stream", server.Stream); err != nil {
+		l.Sugar().Fatalf("funcframework.RegisterHTTPFunctionContext: %v\n", err)
+	}
+	if err := funcframework.RegisterHTTPFunctionContext(ctx, "/v1/", server.Digits); err != nil {
+		l.Sugar().Fatalf("funcframework.RegisterHTTPFunctionContext: %v\n", err)
+	}
+	if err := funcframework.RegisterHTTPFunctionContext(ctx, "/search", server.Search); err != nil {
+		l.Sugar().Fatalf("funcframework.RegisterHTTPFunctionContext: %v\n", err)
+	}
+	if err := funcframework.RegisterHTTPFunctionContext(ctx, "/stats", server.Stats); err != nil {
+		l.Sugar().Fatalf("funcframework.RegisterHTTPFunctionContext: %v\n", err)
+	}
 	// Use PORT environment variable, or default to 8080.
 	port := "8080"
 	if envPort := os.Getenv("PORT"); envPort != "" {
@@ -0,0 +1,181 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/googlecloudplatform/pi-delivery/gen/index"
+	"github.com/googlecloudplatform/pi-delivery/pkg/obj"
+	"github.com/googlecloudplatform/pi-delivery/pkg/obj/gcs"
+	"github.com/googlecloudplatform/pi-delivery/pkg/obj/local"
+	"github.com/googlecloudplatform/pi-delivery/pkg/resultset"
+	"github.com/googlecloudplatform/pi-delivery/pkg/stats"
+)
+
+func main() {
+	radix := flag.Int("radix", 10, "Radix of the digits, 10 or 16")
+	constant := flag.String("constant", resultset.Pi, "Constant of the digits (e.g. e). Constants other than pi need -manifest")
+	manifestLocation := flag.String("manifest", "", "Manifest to take the result set from, a local path or gs://bucket/object. Defaults to the generated index")
+	start := flag.Int64("s", 0, "Start offset, counting the first digit after the decimal point as 0")
+	n := flag.Int64("n", -1, "Number of digits, or -1 for all digits after the start offset")
+	ngram := flag.Int("ngram", stats.DefaultNGramLength, "Length of n-grams")
+	workers := flag.Int("workers", 0, "Number of workers. Defaults to the number of CPUs")
+	taskSize := flag.Int64("task", stats.DefaultTaskSize, "Maximum number of digits per task")
+	outfile := flag.String("o", "-", "Output file")
+	localRoot := flag.String("local", "", "Read from a local directory containing the bucket instead of Cloud Storage")
+	histDir := flag.String("histograms", "", "Write histogram sidecars of the blocks to this directory, to be copied to the bucket, instead of computing stats")
+	granularity := flag.Int64("granularity", stats.DefaultGranularity, "Number of digits per histogram bin")
+	firstBlock := flag.Int64("first-block", 0, "First block to write histograms of")
+	lastBlock := flag.Int64("last-block", -1, "Last block to write histograms of, or -1 for the last block")
+	flag.Parse()
+
+	ctx := context.Background()
+	var client obj.Client
+	var err error
+	if *localRoot != "" {
+		client, err = local.NewClient(*localRoot)
+	} else {
+		client, err = gcs.NewClient(ctx)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "couldn't initialize storage client: %v\n", err)
+		os.Exit(1)
+	}
+	defer client.Close()
+
+	set, bucketName, err := selectResultSet(ctx, client, *manifestLocation, *constant, *radix)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "couldn't select the result set: %v\n", err)
+		os.Exit(1)
+	}
+	end := set.TotalDigits()
+	if *n >= 0 && *start+*n < end {
+		end = *start + *n
+	}
+	bucket := client.Bucket(bucketName)
+
+	if *histDir != "" {
+		if *lastBlock < 0 {
+			*lastBlock = int64(len(set) - 1)
+		}
+		if err := writeHistograms(ctx, set, bucket, *histDir, *firstBlock, *lastBlock, *granularity, *workers); err != nil {
+			fmt.Fprintf(os.Stderr, "couldn't write histograms: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	t := time.Now()
+	s, err := stats.Compute(ctx, set, bucket, *start, end, &stats.Options{
+		NGramLength: *ngram,
+		Workers:     *workers,
+		TaskSize:    *taskSize,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "couldn't compute stats: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "computed stats of %d digits in %v\n", s.N, time.Since(t))
+
+	out := os.Stdout
+	if *outfile != "-" {
+		f, err := os.OpenFile(*outfile, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "couldn't open %s: %v\n", *outfile, err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		out = f
+	}
+	if err := stats.Write(out, s); err != nil {
+		fmt.Fprintf(os.Stderr, "I/O error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// selectResultSet returns the result set of constant in radix and the name of
+// the bucket containing it. It takes them from the manifest at location as the
+// service does, or from the generated index if location is empty.
+func selectResultSet(ctx context.Context, client obj.Client, location, constant string, radix int) (resultset.ResultSet, string, error) {
+	if radix != 10 && radix != 16 {
+		return nil, "", fmt.Errorf("radix must be either 10 or 16: %d", radix)
+	}
+	if location == "" {
+		if resultset.NormalizeConstant(constant) != resultset.Pi {
+			return nil, "", fmt.Errorf("the generated index only has pi, use -manifest for %s", constant)
+		}
+		if radix == 16 {
+			return index.Hexadecimal, index.BucketName, nil
+		}
+		return index.Decimal, index.BucketName, nil
+	}
+	m, err := resultset.LoadManifestLocation(ctx, client, location)
+	if err != nil {
+		return nil, "", err
+	}
+	registry, err := m.Registry()
+	if err != nil {
+		return nil, "", err
+	}
+	set, ok := registry.Get(constant, radix)
+	if !ok {
+		return nil, "", fmt.Errorf("no result set for constant %s radix %d in %s", constant, radix, location)
+	}
+	bucketName := m.BucketName
+	if bucketName == "" {
+		bucketName = index.BucketName
+	}
+	return set, bucketName, nil
+}
+
+// writeHistograms computes the histograms of blocks [first, last] and writes them
+// under dir with their object names. HistogramIndex reads them next to the blocks,
+// so dir must be copied to the root of the bucket as is.
+func writeHistograms(ctx context.Context, set resultset.ResultSet, bucket obj.Bucket, dir string, first, last, granularity int64, workers int) error {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	for b := first; b <= last; b++ {
+		t := time.Now()
+		h, err := stats.ComputeHistogram(ctx, set, bucket, b, granularity, workers)
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(dir, filepath.FromSlash(stats.HistogramObjectName(set[b])))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		if err := stats.WriteHistogram(f, h); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "wrote %s in %v\n", path, time.Since(t))
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"sync"
+
+	"github.com/goccy/go-json"
+	"github.com/googlecloudplatform/pi-delivery/pkg/bbp"
+	"github.com/googlecloudplatform/pi-delivery/pkg/obj"
+	"github.com/googlecloudplatform/pi-delivery/pkg/resultset"
+	"github.com/googlecloudplatform/pi-delivery/pkg/unpack"
+)
+
+// bbpResult is the result of checking an offset with BBP.
+type bbpResult struct {
+	// Offset counts the first digit after the hexadecimal point as 0.
+	Offset int64  `json:"offset"`
+	BBP    string `json:"bbp"`
+	Stored string `json:"stored"`
+	OK     bool   `json:"ok"`
+}
+
+// verifyBBP compares digits of set at random offsets in [0, maxOffset] with
+// those computed by BBP and writes a bbpResult for each offset to enc.
+// It returns false if any of them doesn't match. Offsets where BBP can't
+// determine the digits precisely are skipped.
+func verifyBBP(ctx context.Context, set resultset.ResultSet, bucket obj.Bucket, enc *json.Encoder,
+	samples int, maxOffset int64, digits int, seed int64, workers int) (bool, error) {
+	if set.Radix() != 16 {
+		return false, fmt.Errorf("BBP only computes hexadecimal digits")
+	}
+	if digits < 1 || digits > bbp.MaxDigits {
+		return false, fmt.Errorf("digits must be between 1 and %d", bbp.MaxDigits)
+	}
+	if maxOffset < 0 {
+		return false, fmt.Errorf("max offset must not be negative")
+	}
+	if workers < 1 {
+		return false, fmt.Errorf("workers must be at least 1")
+	}
+	if set.TotalDigits() < int64(digits) {
+		return false, fmt.Errorf("the result set has fewer than %d digits", digits)
+	}
+	if limit := set.TotalDigits() - int64(digits); maxOffset > limit {
+		maxOffset = limit
+	}
+
+	r := rand.New(rand.NewSource(seed))
+	offsets := make(chan int64)
+	go func() {
+		defer close(offsets)
+		for i := 0; i < samples; i++ {
+			offsets <- r.Int63n(maxOffset + 1)
+		}
+	}()
+
+	var mu sync.Mutex
+	ok := true
+	var firstErr error
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for off := range offsets {
+				res, err := checkBBP(ctx, set, bucket, off, digits)
+				mu.Lock()
+				if errors.Is(err, bbp.ErrImprecise) {
+					fmt.Fprintf(os.Stderr, "skipped %d: %v\n", off, err)
+				} else if err != nil {
+					if firstErr == nil {
+						firstErr = err
+					}
+				} else {
+					ok = ok && res.OK
+					if err := enc.Encode(res); err != nil && firstErr == nil {
+						firstErr = err
+					}
+					if !res.OK {
+						fmt.Fprintf(os.Stderr, "mismatch at %d: bbp %s, stored %s\n", res.Offset, res.BBP, res.Stored)
+					}
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return ok, firstErr
+}
+
+func checkBBP(ctx context.Context, set resultset.ResultSet, bucket obj.Bucket, off int64, digits int) (*bbpResult, error) {
+	want, err := bbp.HexDigits(off, digits)
+	if err != nil {
+		return nil, err
+	}
+	rr := set.NewReader(ctx, bucket)
+	defer rr.Close()
+	buf := make([]byte, digits)
+	if _, err := unpack.NewReader(ctx, rr).ReadAtContext(ctx, buf, off); err != nil {
+		return nil, fmt.Errorf("read failed at %d: %w", off, err)
+	}
+	return &bbpResult{
+		Offset: off,
+		BBP:    want,
+		Stored: string(buf),
+		OK:     want == string(buf),
+	}, nil
+}
@@ -0,0 +1,71 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/goccy/go-json"
+	"github.com/googlecloudplatform/pi-delivery/pkg/integrity"
+	"github.com/googlecloudplatform/pi-delivery/pkg/obj"
+	"github.com/googlecloudplatform/pi-delivery/pkg/resultset"
+)
+
+// loadChecksums reads a JSON object mapping object names to base64 CRC32C checksums,
+// e.g. as reported by gsutil hash.
+func loadChecksums(path string) (integrity.ChecksumFunc, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var encoded map[string]string
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+	checksums := make(map[string]uint32, len(encoded))
+	for name, s := range encoded {
+		crc, err := integrity.DecodeCRC32C(s)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		checksums[name] = crc
+	}
+	return func(ctx context.Context, name string) (uint32, bool, error) {
+		crc, ok := checksums[name]
+		return crc, ok, nil
+	}, nil
+}
+
+// verifyBlocks verifies every block of set in bucket and writes
+// an integrity.BlockReport for each block to enc.
+// It returns false if any of them has problems.
+func verifyBlocks(ctx context.Context, set resultset.ResultSet, bucket obj.Bucket, enc *json.Encoder,
+	opts *integrity.Options) (bool, error) {
+	ok := true
+	bad := 0
+	err := integrity.Verify(ctx, set, bucket, opts, func(r *integrity.BlockReport) error {
+		if !r.OK {
+			ok = false
+			bad++
+			fmt.Fprintf(os.Stderr, "bad block %d (%s): %d problems, %d invalid words\n",
+				r.BlockID, r.Name, len(r.Problems), r.InvalidWords)
+		}
+		return enc.Encode(r)
+	})
+	fmt.Fprintf(os.Stderr, "verified %d blocks: %d bad\n", len(set), bad)
+	return ok, err
+}
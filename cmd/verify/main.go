@@ -0,0 +1,104 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/googlecloudplatform/pi-delivery/gen/index"
+	"github.com/googlecloudplatform/pi-delivery/pkg/integrity"
+	"github.com/googlecloudplatform/pi-delivery/pkg/obj"
+	"github.com/googlecloudplatform/pi-delivery/pkg/obj/gcs"
+	"github.com/googlecloudplatform/pi-delivery/pkg/obj/local"
+	"github.com/googlecloudplatform/pi-delivery/pkg/resultset"
+)
+
+func main() {
+	mode := flag.String("mode", "bbp", "Verification mode: bbp or blocks")
+	samples := flag.Int("samples", 100, "bbp: Number of random offsets to check")
+	maxOffset := flag.Int64("max-offset", 1_000_000, "bbp: Maximum offset to sample. The cost of BBP is linear in the offset")
+	digits := flag.Int("digits", 8, "bbp: Number of digits to compare at each offset, up to 16")
+	seed := flag.Int64("seed", 0, "bbp: Random seed. Defaults to the current time")
+	radix := flag.Int("radix", 10, "blocks: Radix of the digits, 10 or 16")
+	checksums := flag.String("checksums", "", "blocks: JSON file mapping object names to base64 CRC32C checksums. Defaults to the checksums in the bucket")
+	maxProblems := flag.Int("max-problems", integrity.DefaultMaxProblems, "blocks: Maximum number of problems reported per block")
+	workers := flag.Int("workers", runtime.NumCPU(), "Number of workers")
+	localRoot := flag.String("local", "", "Read from a local directory containing the bucket instead of Cloud Storage")
+	flag.Parse()
+
+	ctx := context.Background()
+	var client obj.Client
+	var err error
+	if *localRoot != "" {
+		client, err = local.NewClient(*localRoot)
+	} else {
+		client, err = gcs.NewClient(ctx)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "couldn't initialize storage client: %v\n", err)
+		os.Exit(1)
+	}
+	defer client.Close()
+	bucket := client.Bucket(index.BucketName)
+
+	enc := json.NewEncoder(os.Stdout)
+	var ok bool
+	switch *mode {
+	case "bbp":
+		if *seed == 0 {
+			*seed = time.Now().UnixNano()
+		}
+		fmt.Fprintf(os.Stderr, "seed: %d\n", *seed)
+		ok, err = verifyBBP(ctx, index.Hexadecimal, bucket, enc, *samples, *maxOffset, *digits, *seed, *workers)
+	case "blocks":
+		var set resultset.ResultSet
+		switch *radix {
+		case 10:
+			set = index.Decimal
+		case 16:
+			set = index.Hexadecimal
+		default:
+			err = fmt.Errorf("radix must be either 10 or 16: %d", *radix)
+		}
+		opts := &integrity.Options{
+			Checksums:   integrity.ObjectChecksums(bucket),
+			MaxProblems: *maxProblems,
+			Workers:     *workers,
+		}
+		if err == nil && *checksums != "" {
+			opts.Checksums, err = loadChecksums(*checksums)
+		}
+		if err == nil {
+			ok, err = verifyBlocks(ctx, set, bucket, enc, opts)
+		}
+	default:
+		err = fmt.Errorf("unknown mode: %s", *mode)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "verification failed: %v\n", err)
+		os.Exit(2)
+	}
+	if !ok {
+		fmt.Fprintln(os.Stderr, "verification found problems")
+		os.Exit(1)
+	}
+	fmt.Fprintln(os.Stderr, "OK")
+}
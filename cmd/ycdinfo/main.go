@@ -0,0 +1,285 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/goccy/go-json"
+	"github.com/googlecloudplatform/pi-delivery/pkg/integrity"
+	"github.com/googlecloudplatform/pi-delivery/pkg/obj"
+	"github.com/googlecloudplatform/pi-delivery/pkg/obj/gcs"
+	"github.com/googlecloudplatform/pi-delivery/pkg/obj/local"
+	"github.com/googlecloudplatform/pi-delivery/pkg/unpack"
+	"github.com/googlecloudplatform/pi-delivery/pkg/ycd"
+)
+
+// maxHeaderSize is the number of bytes read to parse a header.
+// y-cruncher headers are about 200 bytes long.
+const maxHeaderSize = 64 * 1024
+
+// info is the information about a YCD file.
+type info struct {
+	Location         string      `json:"location"`
+	Header           *ycd.Header `json:"header,omitempty"`
+	FirstDigitOffset int         `json:"firstDigitOffset"`
+	BlockByteLength  int64       `json:"blockByteLength"`
+	// BlockDigits is the number of digits in the block.
+	// It's less than the block size only in the last block.
+	BlockDigits  int64  `json:"blockDigits"`
+	ExpectedSize int64  `json:"expectedSize"`
+	Size         int64  `json:"size"`
+	SizeOK       bool   `json:"sizeOK"`
+	Head         string `json:"head"`
+	Tail         string `json:"tail"`
+	Error        string `json:"error,omitempty"`
+}
+
+// opener opens the objects of the locations given on the command line.
+type opener struct {
+	ctx context.Context
+	gcs obj.Client
+}
+
+// object returns the object at location, which is either
+// a local file path or a Cloud Storage URL (gs://bucket/object).
+func (o *opener) object(location string) (obj.Object, error) {
+	if rest, ok := strings.CutPrefix(location, "gs://"); ok {
+		bucket, object, ok := strings.Cut(rest, "/")
+		if !ok || bucket == "" || object == "" {
+			return nil, fmt.Errorf("invalid object URL: %s", location)
+		}
+		if o.gcs == nil {
+			client, err := gcs.NewClient(o.ctx)
+			if err != nil {
+				return nil, fmt.Errorf("couldn't initialize storage client: %w", err)
+			}
+			o.gcs = client
+		}
+		return o.gcs.Bucket(bucket).Object(object), nil
+	}
+	path, err := filepath.Abs(location)
+	if err != nil {
+		return nil, err
+	}
+	client, err := local.NewClient(filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+	return client.Bucket("").Object(filepath.Base(path)), nil
+}
+
+func (o *opener) Close() error {
+	if o.gcs != nil {
+		return o.gcs.Close()
+	}
+	return nil
+}
+
+// blockDigits returns the number of digits in the block of h.
+// Only the last block has a non-zero TotalDigits, which may make it shorter.
+func blockDigits(h *ycd.Header) int64 {
+	if h.TotalDigits == 0 {
+		return h.BlockSize
+	}
+	remaining := h.TotalDigits - h.BlockID*h.BlockSize
+	if remaining < 0 {
+		return 0
+	}
+	if remaining < h.BlockSize {
+		return remaining
+	}
+	return h.BlockSize
+}
+
+// readDigits returns n digits of the block of f from the digit offset start.
+func readDigits(ctx context.Context, o obj.Object, f *ycd.YCDFile, start, n int64) (string, error) {
+	if n <= 0 {
+		return "", nil
+	}
+	dpw := int64(ycd.DigitsPerWord(f.Header.Radix))
+	first := start / dpw
+	pre := int(start % dpw)
+	words := (start+n+dpw-1)/dpw - first
+
+	rd, err := o.NewRangeReader(ctx, int64(f.FirstDigitOffset)+first*ycd.WordSize, words*ycd.WordSize)
+	if err != nil {
+		return "", err
+	}
+	defer rd.Close()
+	packed, err := io.ReadAll(rd)
+	if err != nil {
+		return "", err
+	}
+	if int64(len(packed)) != words*ycd.WordSize {
+		return "", fmt.Errorf("short read at digit %d: got %d bytes, want %d",
+			start, len(packed), words*ycd.WordSize)
+	}
+	unpacked := make([]byte, words*dpw-int64(pre))
+	m, err := unpack.UnpackBlock(unpacked, packed, f.Header.Radix, pre)
+	if err != nil {
+		return "", err
+	}
+	if int64(m) > n {
+		m = int(n)
+	}
+	return string(unpacked[:m]), nil
+}
+
+// inspect returns the information about the YCD file at location.
+// Errors are recorded in the result so the rest of it is still reported.
+func inspect(ctx context.Context, op *opener, location string, digits int64) *info {
+	r := &info{Location: location}
+	fail := func(err error) *info {
+		r.Error = err.Error()
+		return r
+	}
+	o, err := op.object(location)
+	if err != nil {
+		return fail(err)
+	}
+	attrs, err := o.Attrs(ctx)
+	if err != nil {
+		return fail(err)
+	}
+	r.Size = attrs.Size
+
+	rd, err := o.NewRangeReader(ctx, 0, maxHeaderSize)
+	if err != nil {
+		return fail(err)
+	}
+	f, err := ycd.Parse(rd)
+	rd.Close()
+	if err != nil {
+		return fail(fmt.Errorf("failed to parse the header: %w", err))
+	}
+	r.Header = f.Header
+	r.FirstDigitOffset = f.FirstDigitOffset
+	r.BlockByteLength = f.BlockByteLength()
+	r.BlockDigits = blockDigits(f.Header)
+	r.ExpectedSize = integrity.ExpectedFileSize(f, f.Header.TotalDigits)
+	r.SizeOK = r.Size == r.ExpectedSize
+
+	n := digits
+	if n > r.BlockDigits {
+		n = r.BlockDigits
+	}
+	if r.Head, err = readDigits(ctx, o, f, 0, n); err != nil {
+		return fail(fmt.Errorf("failed to read the first digits: %w", err))
+	}
+	if r.Tail, err = readDigits(ctx, o, f, r.BlockDigits-n, n); err != nil {
+		return fail(fmt.Errorf("failed to read the last digits: %w", err))
+	}
+	return r
+}
+
+// printInfo writes r to w in a human readable form.
+func printInfo(w io.Writer, r *info) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "%s\n", r.Location)
+	if h := r.Header; h != nil {
+		fmt.Fprintf(tw, "  FileVersion:\t%s\n", h.FileVersion)
+		fmt.Fprintf(tw, "  Base:\t%d\n", h.Radix)
+		fmt.Fprintf(tw, "  FirstDigits:\t%s\n", h.FirstDigits)
+		fmt.Fprintf(tw, "  TotalDigits:\t%d\n", h.TotalDigits)
+		fmt.Fprintf(tw, "  Blocksize:\t%d\n", h.BlockSize)
+		fmt.Fprintf(tw, "  BlockID:\t%d\n", h.BlockID)
+		keys := make([]string, 0, len(h.Extra))
+		for k := range h.Extra {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Fprintf(tw, "  %s:\t%s\n", k, h.Extra[k])
+		}
+		fmt.Fprintf(tw, "  Header length:\t%d\n", h.Length)
+		fmt.Fprintf(tw, "  FirstDigitOffset:\t%d\n", r.FirstDigitOffset)
+		fmt.Fprintf(tw, "  BlockByteLength:\t%d\n", r.BlockByteLength)
+		fmt.Fprintf(tw, "  Block digits:\t%d\n", r.BlockDigits)
+		fmt.Fprintf(tw, "  Expected size:\t%d\n", r.ExpectedSize)
+	}
+	if r.Header != nil {
+		size := fmt.Sprint(r.Size)
+		if !r.SizeOK {
+			size += fmt.Sprintf(" (MISMATCH: %+d bytes)", r.Size-r.ExpectedSize)
+		}
+		fmt.Fprintf(tw, "  Actual size:\t%s\n", size)
+	} else if r.Size > 0 {
+		fmt.Fprintf(tw, "  Actual size:\t%d\n", r.Size)
+	}
+	if r.Head != "" || r.Tail != "" {
+		fmt.Fprintf(tw, "  First %d digits:\t%s\n", len(r.Head), r.Head)
+		fmt.Fprintf(tw, "  Last %d digits:\t%s\n", len(r.Tail), r.Tail)
+	}
+	if r.Error != "" {
+		fmt.Fprintf(tw, "  Error:\t%s\n", r.Error)
+	}
+	return tw.Flush()
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] PATH|gs://BUCKET/OBJECT...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	digits := flag.Int64("n", 50, "Number of digits to print from the start and the end of each block")
+	jsonOutput := flag.Bool("json", false, "Write a JSON object per file instead of human readable text")
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *digits < 0 {
+		fmt.Fprintf(os.Stderr, "-n must not be negative: %d\n", *digits)
+		os.Exit(2)
+	}
+
+	ctx := context.Background()
+	op := &opener{ctx: ctx}
+	defer op.Close()
+
+	enc := json.NewEncoder(os.Stdout)
+	ok := true
+	for i, location := range flag.Args() {
+		r := inspect(ctx, op, location, *digits)
+		if r.Error != "" || !r.SizeOK {
+			ok = false
+		}
+		var err error
+		if *jsonOutput {
+			err = enc.Encode(r)
+		} else {
+			if i > 0 {
+				fmt.Println()
+			}
+			err = printInfo(os.Stdout, r)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to write the output: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if !ok {
+		os.Exit(1)
+	}
+}
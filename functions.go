@@ -16,35 +16,67 @@ package rest
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"os"
+	"path"
 	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/GoogleCloudPlatform/functions-framework-go/functions"
 	"github.com/goccy/go-json"
 	"github.com/googlecloudplatform/pi-delivery/gen/index"
+	"github.com/googlecloudplatform/pi-delivery/pkg/obj"
+	"github.com/googlecloudplatform/pi-delivery/pkg/obj/gcs"
+	"github.com/googlecloudplatform/pi-delivery/pkg/resultset"
+	"github.com/googlecloudplatform/pi-delivery/pkg/search"
 	"github.com/googlecloudplatform/pi-delivery/pkg/service"
+	"github.com/googlecloudplatform/pi-delivery/pkg/stats"
 	"go.ajitem.com/zapdriver"
 	"go.uber.org/zap"
 )
 
 var _serv *service.Service
+var _servErr error
 var _servOnce sync.Once
 
 var maxDigitsPerRequest = 1000
+var maxDigitsPerStream = 1_000_000_000
+var maxDigitsPerSearch = 1_000_000_000
+var maxDigitsPerStats = 1_000_000_000
 var bucketName = index.BucketName
+var manifestLocation = ""
+
+const (
+	// maxPatternLength is the maximum length of a pattern for Search.
+	maxPatternLength = 1000
+	// maxMatchesPerSearch is the maximum number of matches Search returns.
+	maxMatchesPerSearch = 1000
+	// defaultDigitsPerSearch is the number of digits Search scans unless
+	// numberOfDigits is given.
+	defaultDigitsPerSearch = 1_000_000
+)
 
 const (
 	envMaxDigitsPerRequest = "PI_MAX_DIGITS_PER_REQUEST"
+	envMaxDigitsPerStream  = "PI_MAX_DIGITS_PER_STREAM"
+	envMaxDigitsPerSearch  = "PI_MAX_DIGITS_PER_SEARCH"
+	envMaxDigitsPerStats   = "PI_MAX_DIGITS_PER_STATS"
 	envBucketName          = "PI_BUCKET_NAME"
+	envManifest            = "PI_MANIFEST"
 )
 
 func init() {
 	functions.HTTP("Get", Get)
+	functions.HTTP("Stream", Stream)
+	functions.HTTP("Digits", Digits)
+	functions.HTTP("Search", Search)
+	functions.HTTP("Stats", Stats)
 	functions.HTTP("NotFound", NotFound)
 	if logger, err := zapdriver.NewProduction(); err != nil {
 		zap.S().Fatalw("zapdriver.NewProduction() failed", "error", err)
@@ -61,20 +93,78 @@ func init() {
 			maxDigitsPerRequest = i
 		}
 	}
+	if s := os.Getenv(envMaxDigitsPerStream); s != "" {
+		if i, err := strconv.Atoi(s); err != nil {
+			zap.S().Error("invalid env value", "name", envMaxDigitsPerStream, "value", s)
+		} else {
+			maxDigitsPerStream = i
+		}
+	}
+	if s := os.Getenv(envMaxDigitsPerSearch); s != "" {
+		if i, err := strconv.Atoi(s); err != nil {
+			zap.S().Error("invalid env value", "name", envMaxDigitsPerSearch, "value", s)
+		} else {
+			maxDigitsPerSearch = i
+		}
+	}
+	if s := os.Getenv(envMaxDigitsPerStats); s != "" {
+		if i, err := strconv.Atoi(s); err != nil {
+			zap.S().Error("invalid env value", "name", envMaxDigitsPerStats, "value", s)
+		} else {
+			maxDigitsPerStats = i
+		}
+	}
 	if s := os.Getenv(envBucketName); s != "" {
 		bucketName = s
 	}
+	manifestLocation = os.Getenv(envManifest)
 	zap.S().Info("Config",
 		"maxDigitsPerRequest", maxDigitsPerRequest,
+		"maxDigitsPerStream", maxDigitsPerStream,
+		"maxDigitsPerSearch", maxDigitsPerSearch,
+		"maxDigitsPerStats", maxDigitsPerStats,
 		"bucketName", bucketName,
+		"manifest", manifestLocation,
 	)
 }
 
-func getService(ctx context.Context) *service.Service {
+func getService() (*service.Service, error) {
 	_servOnce.Do(func() {
-		_serv = service.NewService(ctx, zap.S(), bucketName)
+		ctx := context.Background()
+		client, err := gcs.NewClient(ctx)
+		if err != nil {
+			zap.S().Errorw("Failed to create a new Storage client",
+				"error", err)
+			_servErr = err
+			return
+		}
+		opts := []service.Option{
+			service.WithResultSets(index.Decimal, index.Hexadecimal),
+		}
+		if manifestLocation != "" {
+			m, err := resultset.LoadManifestLocation(ctx, client, manifestLocation)
+			if err != nil {
+				zap.S().Errorw("Failed to load the manifest",
+					"error", err,
+					"manifest", manifestLocation)
+				_servErr = err
+				client.Close()
+				return
+			}
+			opts = append(opts, service.WithManifest(m))
+		}
+		// The bucket name from env takes precedence over the manifest.
+		if os.Getenv(envBucketName) != "" || manifestLocation == "" {
+			opts = append(opts, service.WithBucketName(bucketName))
+		}
+		_serv, _servErr = service.New(client, opts...)
+		if _servErr != nil {
+			zap.S().Errorw("Failed to create a new Service",
+				"error", _servErr)
+			client.Close()
+		}
 	})
-	return _serv
+	return _serv, _servErr
 }
 
 func namedLogger(l *zap.SugaredLogger, name string, req *http.Request) *zap.SugaredLogger {
@@ -108,6 +198,80 @@ func getIntQueryParam(l *zap.SugaredLogger, q url.Values, name string, def int64
 	return i, nil
 }
 
+// digitsRequest is the common parameters of the requests for digits.
+type digitsRequest struct {
+	serv           *service.Service
+	set            resultset.ResultSet
+	start          int64
+	numberOfDigits int64
+}
+
+// parseDigitsRequest parses and validates the query parameters common to
+// the requests for digits (start, numberOfDigits, radix and constant).
+// It writes an error response to res and returns nil if the request is invalid.
+func parseDigitsRequest(l *zap.SugaredLogger, res http.ResponseWriter, req *http.Request, defDigits, maxDigits int64) *digitsRequest {
+	q := req.URL.Query()
+	radix, err := getIntQueryParam(l, q, "radix", 10)
+	if err != nil {
+		writeError(l, res, http.StatusBadRequest, err.Error())
+		return nil
+	}
+	if radix != 10 && radix != 16 {
+		writeError(l, res, http.StatusBadRequest, "radix must be either 10 or 16")
+		return nil
+	}
+
+	constant := q.Get("constant")
+	if constant == "" {
+		constant = resultset.Pi
+	}
+
+	start, err := getIntQueryParam(l, q, "start", 0)
+	if err != nil {
+		writeError(l, res, http.StatusBadRequest, err.Error())
+		return nil
+	}
+	if start < 0 {
+		writeError(l, res, http.StatusBadRequest, "start is negative")
+		return nil
+	}
+
+	numberOfDigits, err := getIntQueryParam(l, q, "numberOfDigits", defDigits)
+	if err != nil {
+		writeError(l, res, http.StatusBadRequest, err.Error())
+		return nil
+	}
+	if numberOfDigits < 0 {
+		writeError(l, res, http.StatusBadRequest, "numberOfDigits is negative")
+		return nil
+	}
+	if numberOfDigits > maxDigits {
+		writeError(l, res, http.StatusBadRequest, "numberOfDigits is too big")
+		return nil
+	}
+
+	serv, err := getService()
+	if err != nil {
+		writeError(l, res, http.StatusInternalServerError, "Internal Server Error")
+		return nil
+	}
+	set, err := serv.ResultSet(constant, int(radix))
+	if err != nil {
+		writeError(l, res, http.StatusBadRequest, err.Error())
+		return nil
+	}
+	if start > set.TotalDigits() {
+		writeError(l, res, http.StatusBadRequest, "start out of range")
+		return nil
+	}
+	return &digitsRequest{
+		serv:           serv,
+		set:            set,
+		start:          start,
+		numberOfDigits: numberOfDigits,
+	}
+}
+
 // GetResponse is the JSON response for Get.
 type GetResponse struct {
 	// Content is a string representation of Pi digits.
@@ -116,10 +280,11 @@ type GetResponse struct {
 }
 
 // Get is the entrypoint for the API.
-// It takes three parameters in the query string:
+// It takes four parameters in the query string:
 //  - start (int64): the digit position to read from.
 //  - numberOfDigits(int64): number of digits to read.
 //  - radix (int): the radix of pi to read. 10 or 16. default 10.
+//  - constant (string): the constant to read (e.g. "e"). default "pi".
 // It returns a JSON response as GetResponse.
 func Get(res http.ResponseWriter, req *http.Request) {
 	l := namedLogger(zap.S(), "Get", req)
@@ -128,67 +293,372 @@ func Get(res http.ResponseWriter, req *http.Request) {
 	l.Info("Get start")
 	res.Header().Set("Access-Control-Allow-Origin", "*")
 
+	r := parseDigitsRequest(l, res, req, 100, int64(maxDigitsPerRequest))
+	if r == nil {
+		return
+	}
+
+	unpacked, err := r.serv.Get(req.Context(), l, r.set, r.start, r.numberOfDigits)
+	if err != nil {
+		writeError(l, res, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	res.Header().Set("Content-Type", "application/json")
+	res.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(res).EncodeWithOption(
+		&GetResponse{Content: string(unpacked)},
+		json.DisableHTMLEscape(),
+	)
+	if err != nil {
+		l.Errorw("json encode failed",
+			"error", err)
+	}
+}
+
+// flushWriter flushes the response after every write so that digits are sent
+// to the client as soon as they are unpacked. Writes block while the client
+// isn't reading, which throttles reading from storage.
+type flushWriter struct {
+	w http.ResponseWriter
+	f http.Flusher
+}
+
+func (fw *flushWriter) Write(p []byte) (int, error) {
+	n, err := fw.w.Write(p)
+	if fw.f != nil {
+		fw.f.Flush()
+	}
+	return n, err
+}
+
+// Stream is the entrypoint for the streaming API.
+// It takes the same parameters as Get but writes the digits as text/plain
+// with chunked transfer encoding, so it allows up to PI_MAX_DIGITS_PER_STREAM digits.
+func Stream(res http.ResponseWriter, req *http.Request) {
+	l := namedLogger(zap.S(), "Stream", req)
+	defer l.Sync()
+
+	l.Info("Stream start")
+	res.Header().Set("Access-Control-Allow-Origin", "*")
+
+	r := parseDigitsRequest(l, res, req, 100, int64(maxDigitsPerStream))
+	if r == nil {
+		return
+	}
+
+	res.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	res.Header().Set("X-Content-Type-Options", "nosniff")
+	res.WriteHeader(http.StatusOK)
+	f, _ := res.(http.Flusher)
+	written, err := r.serv.Stream(req.Context(), l, r.set, &flushWriter{w: res, f: f}, r.start, r.numberOfDigits)
+	if err != nil {
+		// The status is already sent so just log it.
+		// The client can tell the error by the short response.
+		l.Errorw("Stream failed",
+			"error", err,
+			"written", written)
+		return
+	}
+	l.Infow("Stream finished",
+		"written", written)
+}
+
+// SearchResponse is the JSON response for Search.
+type SearchResponse struct {
+	// Matches are the positions of the pattern in ascending order.
+	// Position 0 is the first digit before the decimal point as in Get.
+	Matches []int64 `json:"matches"`
+}
+
+// Search is the entrypoint for the search API.
+// It takes the following parameters in the query string:
+//  - pattern (string): the digits to search for. Required.
+//  - start (int64): the digit position to start searching from. default 0.
+//  - numberOfDigits (int64): number of digits to search, up to PI_MAX_DIGITS_PER_SEARCH. default 1,000,000.
+//  - limit (int): the maximum number of matches to return. default 1.
+//  - radix and constant as in Get.
+// It returns a JSON response as SearchResponse.
+// Only matches that fit entirely within the searched digits are returned.
+func Search(res http.ResponseWriter, req *http.Request) {
+	l := namedLogger(zap.S(), "Search", req)
+	defer l.Sync()
+
+	l.Info("Search start")
+	res.Header().Set("Access-Control-Allow-Origin", "*")
+
 	q := req.URL.Query()
-	radix, err := getIntQueryParam(l, q, "radix", 10)
+	pattern := strings.ToLower(q.Get("pattern"))
+	if pattern == "" {
+		writeError(l, res, http.StatusBadRequest, "pattern is empty")
+		return
+	}
+	if len(pattern) > maxPatternLength {
+		writeError(l, res, http.StatusBadRequest, "pattern is too long")
+		return
+	}
+	limit, err := getIntQueryParam(l, q, "limit", 1)
 	if err != nil {
 		writeError(l, res, http.StatusBadRequest, err.Error())
 		return
 	}
-	if radix != 10 && radix != 16 {
-		writeError(l, res, http.StatusBadRequest, "radix must be either 10 or 16")
+	if limit <= 0 || limit > maxMatchesPerSearch {
+		writeError(l, res, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxMatchesPerSearch))
 		return
 	}
-	set := index.Decimal
-	if radix == 16 {
-		set = index.Hexadecimal
+
+	defDigits := int64(defaultDigitsPerSearch)
+	if defDigits > int64(maxDigitsPerSearch) {
+		defDigits = int64(maxDigitsPerSearch)
+	}
+	r := parseDigitsRequest(l, res, req, defDigits, int64(maxDigitsPerSearch))
+	if r == nil {
+		return
+	}
+	if err := search.ValidatePattern([]byte(pattern), r.set.Radix()); err != nil {
+		writeError(l, res, http.StatusBadRequest, err.Error())
+		return
 	}
 
-	start, err := getIntQueryParam(l, q, "start", 0)
+	matches, err := r.serv.Search(req.Context(), l, r.set, []byte(pattern), r.start, r.numberOfDigits, int(limit))
+	if err != nil {
+		writeError(l, res, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	res.Header().Set("Content-Type", "application/json")
+	res.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(res).Encode(&SearchResponse{Matches: matches}); err != nil {
+		l.Errorw("json encode failed",
+			"error", err)
+	}
+}
+
+// CountsResponse is the JSON response for Stats with a range.
+type CountsResponse struct {
+	Start int64 `json:"start"`
+	End   int64 `json:"end"`
+	// Counts are the number of occurrences of each digit.
+	Counts []int64 `json:"counts"`
+}
+
+// Stats is the entrypoint for the statistics API.
+// It takes radix and constant in the query string as in Get and returns
+// the precomputed statistics of all digits as JSON (see stats.Summary).
+// If start or end is given, it returns the digit counts in [start, end) as
+// CountsResponse instead. Positions are the same as Get; the first digit
+// (position 0) is 3 before the decimal point.
+// Counts are computed from the histogram sidecars and a scan of up to
+// PI_MAX_DIGITS_PER_STATS digits at the edges.
+// It returns 404 if the statistics haven't been computed.
+func Stats(res http.ResponseWriter, req *http.Request) {
+	l := namedLogger(zap.S(), "Stats", req)
+	defer l.Sync()
+
+	res.Header().Set("Access-Control-Allow-Origin", "*")
+
+	q := req.URL.Query()
+	radix, err := getIntQueryParam(l, q, "radix", 10)
 	if err != nil {
 		writeError(l, res, http.StatusBadRequest, err.Error())
 		return
 	}
-	if start < 0 {
-		writeError(l, res, http.StatusBadRequest, "start is negative")
+	constant := q.Get("constant")
+	if constant == "" {
+		constant = resultset.Pi
+	}
+
+	serv, err := getService()
+	if err != nil {
+		writeError(l, res, http.StatusInternalServerError, "Internal Server Error")
 		return
 	}
-	if start > set.TotalDigits() {
-		writeError(l, res, http.StatusBadRequest, "start out of range")
+	set, err := serv.ResultSet(constant, int(radix))
+	if err != nil {
+		writeError(l, res, http.StatusBadRequest, err.Error())
+		return
+	}
+	if q.Get("start") != "" || q.Get("end") != "" {
+		writeCounts(l, res, req, serv, set)
+		return
+	}
+
+	s, err := serv.Stats(req.Context(), set)
+	if errors.Is(err, obj.ErrObjectNotExist) {
+		writeError(l, res, http.StatusNotFound, "statistics are not available")
+		return
+	}
+	if err != nil {
+		l.Errorw("Stats failed", "error", err)
+		writeError(l, res, http.StatusInternalServerError, "Internal Server Error")
 		return
 	}
 
-	numberOfDigits, err := getIntQueryParam(l, q, "numberOfDigits", 100)
+	res.Header().Set("Content-Type", "application/json")
+	res.Header().Set("Cache-Control", "public, max-age=3600")
+	res.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(res).Encode(s.Summary()); err != nil {
+		l.Errorw("json encode failed",
+			"error", err)
+	}
+}
+
+func writeCounts(l *zap.SugaredLogger, res http.ResponseWriter, req *http.Request, serv *service.Service, set resultset.ResultSet) {
+	q := req.URL.Query()
+	start, err := getIntQueryParam(l, q, "start", 0)
 	if err != nil {
 		writeError(l, res, http.StatusBadRequest, err.Error())
 		return
 	}
-	if numberOfDigits < 0 {
-		writeError(l, res, http.StatusBadRequest, "numberOfDigits is negative")
+	end, err := getIntQueryParam(l, q, "end", set.TotalDigits()+1)
+	if err != nil {
+		writeError(l, res, http.StatusBadRequest, err.Error())
 		return
 	}
-	if numberOfDigits > int64(maxDigitsPerRequest) {
-		writeError(l, res, http.StatusBadRequest, "numberOfDigits is too big")
+	if start < 0 || end > set.TotalDigits()+1 || start > end {
+		writeError(l, res, http.StatusBadRequest, "invalid range")
 		return
 	}
 
-	unpacked, err := getService(req.Context()).
-		Get(req.Context(), l, set, start, numberOfDigits)
+	counts, err := serv.CountDigits(req.Context(), set, start, end, int64(maxDigitsPerStats))
+	if errors.Is(err, obj.ErrObjectNotExist) {
+		writeError(l, res, http.StatusNotFound, "statistics are not available")
+		return
+	}
+	if errors.Is(err, stats.ErrScanTooLarge) {
+		writeError(l, res, http.StatusBadRequest, err.Error())
+		return
+	}
 	if err != nil {
+		l.Errorw("CountDigits failed", "error", err)
 		writeError(l, res, http.StatusInternalServerError, "Internal Server Error")
 		return
 	}
+
 	res.Header().Set("Content-Type", "application/json")
 	res.WriteHeader(http.StatusOK)
-	err = json.NewEncoder(res).EncodeWithOption(
-		&GetResponse{Content: string(unpacked)},
-		json.DisableHTMLEscape(),
-	)
-	if err != nil {
+	if err := json.NewEncoder(res).Encode(&CountsResponse{Start: start, End: end, Counts: counts}); err != nil {
 		l.Errorw("json encode failed",
 			"error", err)
 	}
 }
 
+// digitsFileRadix maps the file names of the raw digits resource to radixes.
+var digitsFileRadix = map[string]int{
+	"decimal.txt":     10,
+	"hexadecimal.txt": 16,
+}
+
+// parseDigitsPath parses the constant and radix from a path
+// like /v1/pi/decimal.txt.
+func parseDigitsPath(p string) (string, int, bool) {
+	radix, ok := digitsFileRadix[path.Base(p)]
+	if !ok {
+		return "", 0, false
+	}
+	constant := path.Base(path.Dir(p))
+	if constant == "." || constant == "/" {
+		return "", 0, false
+	}
+	return constant, radix, true
+}
+
+// errTooManyDigits is returned when a response exceeds PI_MAX_DIGITS_PER_STREAM.
+var errTooManyDigits = errors.New("too many digits requested")
+
+// limitedResponseWriter checks the Content-Length of a response when its header
+// is written and replaces the response with 413 if it's more than max bytes.
+// Nothing of the rejected response is sent to the client.
+type limitedResponseWriter struct {
+	http.ResponseWriter
+	l        *zap.SugaredLogger
+	max      int64
+	rejected bool
+}
+
+func (w *limitedResponseWriter) WriteHeader(code int) {
+	if code != http.StatusOK && code != http.StatusPartialContent {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	n, err := strconv.ParseInt(w.Header().Get("Content-Length"), 10, 64)
+	if err == nil && n <= w.max {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	w.rejected = true
+	h := w.Header()
+	h.Del("Content-Length")
+	h.Del("Content-Range")
+	h.Del("Content-Type")
+	writeError(w.l, w.ResponseWriter, http.StatusRequestEntityTooLarge,
+		fmt.Sprintf("%v: request a range of at most %d digits", errTooManyDigits, w.max))
+}
+
+func (w *limitedResponseWriter) Write(p []byte) (int, error) {
+	if w.rejected {
+		return 0, errTooManyDigits
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// Digits is the entrypoint for the raw digits resource.
+// Each result set is served as a text file at /v1/{constant}/decimal.txt or
+// /v1/{constant}/hexadecimal.txt, which contains the digits after the decimal point.
+// It supports HEAD and Range requests. A response is limited to
+// PI_MAX_DIGITS_PER_STREAM digits. A larger request fails with 413 before any
+// digits are sent. HEAD isn't limited and reports the full Content-Length.
+func Digits(res http.ResponseWriter, req *http.Request) {
+	l := namedLogger(zap.S(), "Digits", req)
+	defer l.Sync()
+
+	res.Header().Set("Access-Control-Allow-Origin", "*")
+	res.Header().Set("Access-Control-Expose-Headers", "Accept-Ranges, Content-Length, Content-Range")
+
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		res.Header().Set("Allow", "GET, HEAD")
+		writeError(l, res, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	constant, radix, ok := parseDigitsPath(req.URL.Path)
+	if !ok {
+		NotFound(res, req)
+		return
+	}
+
+	serv, err := getService()
+	if err != nil {
+		writeError(l, res, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	serveDigits(l, res, req, serv, constant, radix, int64(maxDigitsPerStream))
+}
+
+// serveDigits serves the digits of constant in radix from serv
+// with up to maxDigits digits in a response other than HEAD.
+func serveDigits(l *zap.SugaredLogger, res http.ResponseWriter, req *http.Request, serv *service.Service,
+	constant string, radix int, maxDigits int64) {
+	set, err := serv.ResultSet(constant, radix)
+	if err != nil {
+		NotFound(res, req)
+		return
+	}
+
+	rd := serv.NewReader(req.Context(), set)
+	defer rd.Close()
+
+	res.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	res.Header().Set("X-Content-Type-Options", "nosniff")
+	var w http.ResponseWriter = res
+	if req.Method != http.MethodHead {
+		// A HEAD response has no body, so clients can see the full length.
+		w = &limitedResponseWriter{
+			ResponseWriter: res,
+			l:              l,
+			max:            maxDigits,
+		}
+	}
+	http.ServeContent(w, req, "", time.Time{}, rd)
+}
+
 // NotFound returns 404 for all requests.
 // This is necessary because LB can't return 404 by itself.
 // https://issuetracker.google.com/160192483
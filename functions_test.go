@@ -15,6 +15,7 @@
 package rest
 
 import (
+	"encoding/binary"
 	"fmt"
 	"io"
 	"net/http"
@@ -25,6 +26,11 @@ import (
 
 	"github.com/goccy/go-json"
 	"github.com/google/go-cmp/cmp"
+	"github.com/googlecloudplatform/pi-delivery/pkg/obj/memory"
+	"github.com/googlecloudplatform/pi-delivery/pkg/resultset"
+	"github.com/googlecloudplatform/pi-delivery/pkg/service"
+	"github.com/googlecloudplatform/pi-delivery/pkg/ycd"
+	"go.uber.org/zap"
 )
 
 func TestRest_Get(t *testing.T) {
@@ -145,6 +151,96 @@ func TestGet_BadRequests(t *testing.T) {
 	}
 }
 
+func TestRest_Stream(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		radix    int
+		start, n int64
+		want     string
+	}{
+		{10, 0, 0, ""},
+		{10, 0, 50, "31415926535897932384626433832795028841971693993751"},
+		{10, 50_000_000_000_000 - 1, 2, "68"},
+		{16, 1, 50, "243f6a8885a308d313198a2e03707344a4093822299f31d008"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(fmt.Sprintf("Radix %d Start %d N %d", tc.radix, tc.start, tc.n), func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(http.MethodGet, "/Stream", nil)
+			q := req.URL.Query()
+			q.Add("start", strconv.FormatInt(tc.start, 10))
+			q.Add("numberOfDigits", strconv.FormatInt(tc.n, 10))
+			q.Add("radix", strconv.Itoa(tc.radix))
+			req.URL.RawQuery = q.Encode()
+
+			recorder := httptest.NewRecorder()
+			Stream(recorder, req)
+
+			res := recorder.Result()
+			if got, want := res.StatusCode, http.StatusOK; got != want {
+				t.Errorf("StatusCode = got %d, want %d", got, want)
+			}
+			if got, want := res.Header.Get("Content-Type"), "text/plain; charset=utf-8"; got != want {
+				t.Errorf("Content-Type = got %s, want %s", got, want)
+			}
+			got, err := io.ReadAll(res.Body)
+			if err != nil {
+				t.Errorf("ReadAll() failed: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, string(got)); diff != "" {
+				t.Errorf("Response = (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestStream_BadRequests(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		radix    string
+		start, n string
+		want     string
+	}{
+		{"42", "0", "", "radix"},
+		{"", "-1", "", "negative"},
+		{"", "123", "-1", "negative"},
+		{"", "", "1000000001", "too big"},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(fmt.Sprintf("Radix %v Start %v N %v", tc.radix, tc.start, tc.n), func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(http.MethodGet, "/Stream", nil)
+			q := req.URL.Query()
+			q.Add("start", tc.start)
+			q.Add("numberOfDigits", tc.n)
+			q.Add("radix", tc.radix)
+			req.URL.RawQuery = q.Encode()
+
+			recorder := httptest.NewRecorder()
+			Stream(recorder, req)
+
+			res := recorder.Result()
+			if got, want := res.StatusCode, http.StatusBadRequest; got != want {
+				t.Errorf("StatusCode = got %d, want %d", got, want)
+			}
+			got, err := io.ReadAll(res.Body)
+			if err != nil {
+				t.Errorf("ReadAll() failed: %v", err)
+			}
+			if !strings.Contains(string(got), tc.want) {
+				t.Errorf("Response got %s, should contain %s", string(got), tc.want)
+			}
+		})
+	}
+}
+
 func TestRest_NotFound(t *testing.T) {
 	t.Parallel()
 
@@ -167,3 +263,251 @@ func TestRest_NotFound(t *testing.T) {
 		t.Errorf("Response = (-want, +got):\n%s", diff)
 	}
 }
+
+func TestDigits_BadRequests(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		method, path string
+		code         int
+	}{
+		{http.MethodPost, "/v1/pi/decimal.txt", http.StatusMethodNotAllowed},
+		{http.MethodGet, "/v1/pi/octal.txt", http.StatusNotFound},
+		{http.MethodGet, "/decimal.txt", http.StatusNotFound},
+		{http.MethodHead, "/v1/pi/", http.StatusNotFound},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(fmt.Sprintf("%s %s", tc.method, tc.path), func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			recorder := httptest.NewRecorder()
+			Digits(recorder, req)
+
+			if got, want := recorder.Result().StatusCode, tc.code; got != want {
+				t.Errorf("StatusCode = got %d, want %d", got, want)
+			}
+		})
+	}
+}
+
+// newDigitsTestService returns a Service backed by an in-memory bucket
+// that contains the first 38 decimal digits of pi after the decimal point.
+func newDigitsTestService(t *testing.T) *service.Service {
+	t.Helper()
+	set := resultset.ResultSet{
+		{
+			Header: &ycd.Header{
+				FileVersion: "1.1.0",
+				Radix:       10,
+				FirstDigits: "3.14159265358979323846264338327950288419716939937510",
+				BlockSize:   38,
+				BlockID:     0,
+			},
+			Name:             "Pi - Dec - Chudnovsky/Pi - Dec - Chudnovsky - 0.ycd",
+			FirstDigitOffset: 201,
+		},
+	}
+	client := memory.NewClient()
+	data := make([]byte, set[0].FirstDigitOffset)
+	data = binary.LittleEndian.AppendUint64(data, 1415926535897932384)
+	data = binary.LittleEndian.AppendUint64(data, 6264338327950288419)
+	client.MemoryBucket("test-bucket").Put(set[0].Name, data)
+	serv, err := service.New(client,
+		service.WithBucketName("test-bucket"),
+		service.WithResultSets(set),
+		service.WithCachePolicy(service.CacheNone),
+	)
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := serv.Close(); err != nil {
+			t.Errorf("Close() failed: %v", err)
+		}
+	})
+	return serv
+}
+
+func TestDigits_Limit(t *testing.T) {
+	t.Parallel()
+
+	const digits = "14159265358979323846264338327950288419"
+	serv := newDigitsTestService(t)
+	testCases := []struct {
+		name             string
+		method           string
+		header           map[string]string
+		max              int64
+		code             int
+		body             string
+		wantContentRange string
+	}{
+		{"all", http.MethodGet, nil, 100, http.StatusOK, digits, ""},
+		{"all over the limit", http.MethodGet, nil, 10, http.StatusRequestEntityTooLarge, "", ""},
+		// HEAD has no body, so it isn't limited.
+		{"head over the limit", http.MethodHead, nil, 10, http.StatusOK, "", ""},
+		{"head range over the limit", http.MethodHead, map[string]string{"Range": "bytes=0-10"}, 10, http.StatusPartialContent, "", "bytes 0-10/38"},
+		{"range", http.MethodGet, map[string]string{"Range": "bytes=0-9"}, 10, http.StatusPartialContent, digits[:10], "bytes 0-9/38"},
+		{"suffix range", http.MethodGet, map[string]string{"Range": "bytes=-5"}, 10, http.StatusPartialContent, digits[33:], "bytes 33-37/38"},
+		{"range over the limit", http.MethodGet, map[string]string{"Range": "bytes=0-10"}, 10, http.StatusRequestEntityTooLarge, "", ""},
+		{"open range over the limit", http.MethodGet, map[string]string{"Range": "bytes=20-"}, 10, http.StatusRequestEntityTooLarge, "", ""},
+		// The Range is ignored because the If-Range doesn't match.
+		{"if-range", http.MethodGet, map[string]string{"Range": "bytes=0-9", "If-Range": `"etag"`}, 10, http.StatusRequestEntityTooLarge, "", ""},
+		{"range out of bounds", http.MethodGet, map[string]string{"Range": "bytes=38-"}, 10, http.StatusRequestedRangeNotSatisfiable, "", "bytes */38"},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(tc.method, "/v1/pi/decimal.txt", nil)
+			for k, v := range tc.header {
+				req.Header.Set(k, v)
+			}
+			recorder := httptest.NewRecorder()
+			serveDigits(zap.NewNop().Sugar(), recorder, req, serv, "pi", 10, tc.max)
+
+			res := recorder.Result()
+			body, err := io.ReadAll(res.Body)
+			if err != nil {
+				t.Fatalf("ReadAll() failed: %v", err)
+			}
+			if got, want := res.StatusCode, tc.code; got != want {
+				t.Errorf("StatusCode = got %d, want %d", got, want)
+			}
+			if got, want := res.Header.Get("Content-Range"), tc.wantContentRange; got != want {
+				t.Errorf("Content-Range = got %q, want %q", got, want)
+			}
+			if cl := res.Header.Get("Content-Length"); cl != "" && tc.method == http.MethodGet {
+				if n, err := strconv.Atoi(cl); err != nil || n != len(body) {
+					t.Errorf("Content-Length = got %s, want %d", cl, len(body))
+				}
+			}
+			if tc.method == http.MethodHead {
+				want := strconv.Itoa(len(digits))
+				if tc.code == http.StatusPartialContent {
+					want = "11"
+				}
+				if got := res.Header.Get("Content-Length"); got != want {
+					t.Errorf("Content-Length = got %q, want %q", got, want)
+				}
+			}
+			if tc.code >= 300 {
+				if strings.Contains(string(body), digits[:10]) {
+					t.Errorf("error body = got %q, want no digits", body)
+				}
+				return
+			}
+			if tc.method == http.MethodGet && string(body) != tc.body {
+				t.Errorf("body = got %q, want %q", body, tc.body)
+			}
+		})
+	}
+}
+
+func TestRest_Search(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		radix    int
+		pattern  string
+		start, n int64
+		limit    int
+		want     []int64
+	}{
+		{10, "314159", 0, 1000, 1, []int64{0}},
+		{10, "999999", 0, 1_000_000, 1, []int64{762}},
+		{10, "26", 0, 30, 3, []int64{6, 21}},
+		{16, "243F6A", 0, 100, 1, []int64{1}},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(fmt.Sprintf("Radix %d Pattern %s Start %d N %d", tc.radix, tc.pattern, tc.start, tc.n), func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(http.MethodGet, "/Search", nil)
+			q := req.URL.Query()
+			q.Add("pattern", tc.pattern)
+			q.Add("start", strconv.FormatInt(tc.start, 10))
+			q.Add("numberOfDigits", strconv.FormatInt(tc.n, 10))
+			q.Add("limit", strconv.Itoa(tc.limit))
+			q.Add("radix", strconv.Itoa(tc.radix))
+			req.URL.RawQuery = q.Encode()
+
+			recorder := httptest.NewRecorder()
+			Search(recorder, req)
+
+			res := recorder.Result()
+			if got, want := res.StatusCode, http.StatusOK; got != want {
+				t.Errorf("StatusCode = got %d, want %d", got, want)
+			}
+			var got SearchResponse
+			if err := json.NewDecoder(res.Body).Decode(&got); err != nil {
+				t.Fatalf("Decode() failed: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got.Matches); diff != "" {
+				t.Errorf("Matches = (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSearch_BadRequests(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		pattern, limit string
+		radix          string
+		want           string
+	}{
+		{"", "", "", "empty"},
+		{strings.Repeat("1", 1001), "", "", "too long"},
+		{"123", "0", "", "limit"},
+		{"123", "1001", "", "limit"},
+		{"123", "abc", "", "limit"},
+		{"123", "", "8", "radix"},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(fmt.Sprintf("Pattern %.10s Limit %v Radix %v", tc.pattern, tc.limit, tc.radix), func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(http.MethodGet, "/Search", nil)
+			q := req.URL.Query()
+			q.Add("pattern", tc.pattern)
+			q.Add("limit", tc.limit)
+			q.Add("radix", tc.radix)
+			req.URL.RawQuery = q.Encode()
+
+			recorder := httptest.NewRecorder()
+			Search(recorder, req)
+
+			res := recorder.Result()
+			if got, want := res.StatusCode, http.StatusBadRequest; got != want {
+				t.Errorf("StatusCode = got %d, want %d", got, want)
+			}
+			got, err := io.ReadAll(res.Body)
+			if err != nil {
+				t.Errorf("ReadAll() failed: %v", err)
+			}
+			if !strings.Contains(string(got), tc.want) {
+				t.Errorf("Response got %s, should contain %s", string(got), tc.want)
+			}
+		})
+	}
+}
+
+func TestStats_BadRequests(t *testing.T) {
+	t.Parallel()
+
+	req := httptest.NewRequest(http.MethodGet, "/Stats?radix=abc", nil)
+	recorder := httptest.NewRecorder()
+	Stats(recorder, req)
+
+	if got, want := recorder.Result().StatusCode, http.StatusBadRequest; got != want {
+		t.Errorf("StatusCode = got %d, want %d", got, want)
+	}
+}
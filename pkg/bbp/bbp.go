@@ -0,0 +1,167 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bbp computes hexadecimal digits of pi at arbitrary positions with
+// the Bailey-Borwein-Plouffe formula, without computing the preceding digits.
+//
+//	pi = sum_{k=0}^{inf} 16^-k (4/(8k+1) - 2/(8k+4) - 1/(8k+5) - 1/(8k+6))
+//
+// The fractional parts are accumulated in 128-bit fixed point. Each term is
+// truncated, so the result at position n is off by less than 8(n+34) * 2^-128,
+// which leaves about 95 accurate bits for positions up to 10^9. That is more
+// than MaxDigits digits, but a carry can still propagate into them if the
+// following digits are a long run of 0 or f. HexDigits returns ErrImprecise
+// rather than digits that the error could change. The cost is linear in the
+// position; positions around 10^7 take seconds.
+package bbp
+
+import (
+	"errors"
+	"math/bits"
+)
+
+// MaxDigits is the maximum number of digits HexDigits returns at a time.
+const MaxDigits = 16
+
+// ErrImprecise is returned if the computed digits are not guaranteed to be correct.
+var ErrImprecise = errors.New("bbp: digits are not precise enough")
+
+// uint128 is a fixed point fraction in [0, 1) with 128 bits.
+// Arithmetic wraps around, which is modulo 1.
+type uint128 struct {
+	hi, lo uint64
+}
+
+func (a uint128) add(b uint128) uint128 {
+	lo, carry := bits.Add64(a.lo, b.lo, 0)
+	hi, _ := bits.Add64(a.hi, b.hi, carry)
+	return uint128{hi, lo}
+}
+
+func (a uint128) sub(b uint128) uint128 {
+	lo, borrow := bits.Sub64(a.lo, b.lo, 0)
+	hi, _ := bits.Sub64(a.hi, b.hi, borrow)
+	return uint128{hi, lo}
+}
+
+func (a uint128) shl(n uint) uint128 {
+	return uint128{a.hi<<n | a.lo>>(64-n), a.lo << n}
+}
+
+// frac returns r/d in fixed point. r must be less than d.
+func frac(r, d uint64) uint128 {
+	hi, rem := bits.Div64(r, 0, d)
+	lo, _ := bits.Div64(rem, 0, d)
+	return uint128{hi, lo}
+}
+
+// mulMod returns a*b mod m.
+func mulMod(a, b, m uint64) uint64 {
+	if m <= 1<<32 {
+		return a * b % m
+	}
+	hi, lo := bits.Mul64(a, b)
+	return bits.Rem64(hi, lo, m)
+}
+
+// powMod returns 16^e mod m.
+func powMod(e, m uint64) uint64 {
+	if m == 1 {
+		return 0
+	}
+	r, b := uint64(1), uint64(16)%m
+	for ; e > 0; e >>= 1 {
+		if e&1 == 1 {
+			r = mulMod(r, b, m)
+		}
+		b = mulMod(b, b, m)
+	}
+	return r
+}
+
+// series returns the fractional part of sum_{k=0}^{inf} 16^(n-k)/(8k+j).
+func series(n, j uint64) uint128 {
+	var s uint128
+	for k := uint64(0); k <= n; k++ {
+		d := 8*k + j
+		s = s.add(frac(powMod(n-k, d), d))
+	}
+	// 16^(n-k) < 2^-128 for k > n+32.
+	for k := n + 1; k <= n+32; k++ {
+		// 16^(n-k) = 2^(128-4(k-n)) in fixed point.
+		shift := 128 - 4*(k-n)
+		var num uint128
+		if shift >= 64 {
+			num.hi = 1 << (shift - 64)
+		} else {
+			num.lo = 1 << shift
+		}
+		s = s.add(num.div(8*k + j))
+	}
+	return s
+}
+
+// div returns a/d.
+func (a uint128) div(d uint64) uint128 {
+	hi, r := a.hi/d, a.hi%d
+	lo, _ := bits.Div64(r, a.lo, d)
+	return uint128{hi, lo}
+}
+
+// fraction returns the fractional part of 16^n * pi in fixed point.
+// The result is off by at most errorBound(n) units of 2^-128.
+func fraction(n uint64) uint128 {
+	s1 := series(n, 1)
+	s4 := series(n, 4)
+	s5 := series(n, 5)
+	s6 := series(n, 6)
+	return s1.shl(2).sub(s4.shl(1)).sub(s5).sub(s6)
+}
+
+// errorBound returns the maximum error of fraction(n) in units of 2^-128.
+// Each series drops less than 2^-128 in each of its n+33 terms and the tail,
+// and fraction adds 4+2+1+1 of them.
+func errorBound(n uint64) uint64 {
+	return 8 * (n + 34)
+}
+
+// hexDigits returns the first count digits of f. It returns ErrImprecise if
+// they could be different for a value within e units of f.
+func hexDigits(f uint128, e uint64, count int) (string, error) {
+	shift := uint(64 - 4*count)
+	lo, hi := f.sub(uint128{0, e}), f.add(uint128{0, e})
+	if lo.hi>>shift != hi.hi>>shift {
+		return "", ErrImprecise
+	}
+	buf := make([]byte, count)
+	for i := range buf {
+		buf[i] = "0123456789abcdef"[f.hi>>60]
+		f = f.shl(4)
+	}
+	return string(buf), nil
+}
+
+// HexDigits returns count hexadecimal digits of pi in lowercase starting at
+// position n, where position 0 is the first digit after the hexadecimal point (2).
+// count must be between 1 and MaxDigits. It returns ErrImprecise if the
+// digits can't be determined within the precision; try a smaller count.
+func HexDigits(n int64, count int) (string, error) {
+	if n < 0 {
+		return "", errors.New("bbp: negative position")
+	}
+	if count < 1 || count > MaxDigits {
+		return "", errors.New("bbp: count must be between 1 and 16")
+	}
+	return hexDigits(fraction(uint64(n)), errorBound(uint64(n)), count)
+}
@@ -0,0 +1,131 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bbp
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// The first 128 hexadecimal digits of pi after the point.
+const testHexDigits = "243f6a8885a308d313198a2e03707344a4093822299f31d0082efa98ec4e6c89452821e638d01377be5466cf34e90c6cc0ac29b7c97c50dd3f84d5b5b5470917"
+
+func TestHexDigits(t *testing.T) {
+	t.Parallel()
+
+	for n := 0; n+MaxDigits <= len(testHexDigits); n++ {
+		got, err := HexDigits(int64(n), MaxDigits)
+		if err != nil {
+			t.Fatalf("HexDigits(%d) failed: %v", n, err)
+		}
+		if want := testHexDigits[n : n+MaxDigits]; got != want {
+			t.Errorf("HexDigits(%d) = got %s, want %s", n, got, want)
+		}
+	}
+}
+
+func TestHexDigits_Large(t *testing.T) {
+	t.Parallel()
+
+	// From Bailey, Borwein and Plouffe (1997). The paper lists the digits
+	// after position 10^6, counting the first digit after the point as 1.
+	testCases := []struct {
+		n    int64
+		want string
+	}{
+		{999_999, "26c65e52cb4593"},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(fmt.Sprint(tc.n), func(t *testing.T) {
+			t.Parallel()
+			got, err := HexDigits(tc.n, len(tc.want))
+			if err != nil {
+				t.Fatalf("HexDigits() failed: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("HexDigits(%d) = got %s, want %s", tc.n, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestHexDigits_Errors(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		n     int64
+		count int
+	}{{-1, 1}, {0, 0}, {0, MaxDigits + 1}} {
+		if _, err := HexDigits(tc.n, tc.count); err == nil {
+			t.Errorf("HexDigits(%d, %d) error = got nil, want non-nil", tc.n, tc.count)
+		}
+	}
+}
+
+func TestHexDigits_Imprecise(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		f     uint128
+		e     uint64
+		count int
+		want  string
+		err   error
+	}{
+		{uint128{0x243f6a8885a308d3, 1 << 63}, 8, MaxDigits, "243f6a8885a308d3", nil},
+		// The error could borrow from the last digit.
+		{uint128{0x243f6a8885a308d3, 0}, 1, MaxDigits, "", ErrImprecise},
+		{uint128{0x243f6a8885a308d3, 0}, 1, MaxDigits - 1, "243f6a8885a308d", nil},
+		// The error could carry through the run of f into the first digit.
+		{uint128{0x2fffffffffffffff, ^uint64(0)}, 1, 1, "", ErrImprecise},
+		{uint128{^uint64(0), ^uint64(0)}, 1, 1, "", ErrImprecise},
+	}
+	for _, tc := range testCases {
+		got, err := hexDigits(tc.f, tc.e, tc.count)
+		if !errors.Is(err, tc.err) {
+			t.Errorf("hexDigits(%x, %d, %d) error = got %v, want %v", tc.f, tc.e, tc.count, err, tc.err)
+		}
+		if got != tc.want {
+			t.Errorf("hexDigits(%x, %d, %d) = got %s, want %s", tc.f, tc.e, tc.count, got, tc.want)
+		}
+	}
+}
+
+func TestPowMod(t *testing.T) {
+	t.Parallel()
+
+	// 16^e mod m for m larger than 2^32 uses 128-bit multiplication.
+	testCases := []struct {
+		e, m, want uint64
+	}{
+		{0, 7, 1},
+		{5, 1, 0},
+		{10, 1_000_000_007, 511620083},
+		{123456789, 1<<61 - 1, 512},
+	}
+	for _, tc := range testCases {
+		if got := powMod(tc.e, tc.m); got != tc.want {
+			t.Errorf("powMod(%d, %d) = got %d, want %d", tc.e, tc.m, got, tc.want)
+		}
+	}
+}
+
+func BenchmarkHexDigits(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		HexDigits(100_000, MaxDigits)
+	}
+}
@@ -20,10 +20,10 @@ import (
 	"sync"
 
 	"github.com/googlecloudplatform/pi-delivery/pkg/resultset"
+	"github.com/googlecloudplatform/pi-delivery/pkg/ycd"
 )
 
 const cacheSize = 1 * 1024 * 1024 // 1 MiB
-var radixes = map[int]int{10: 0, 16: 1}
 
 type cache struct {
 	once  sync.Once
@@ -31,12 +31,24 @@ type cache struct {
 	cache []byte
 }
 
-var _cache = make([]cache, len(radixes))
+// Cache holds the first bytes of result sets read with CachedReaders.
+// Result sets are told apart by their first file, not the object name, so
+// a Cache must not be shared among readers of different buckets.
+type Cache struct {
+	caches sync.Map
+}
+
+// NewCache returns a new empty Cache.
+func NewCache() *Cache {
+	return new(Cache)
+}
 
 // UpstreamReader is the reader CachedReader reads from.
 type UpstreamReader interface {
 	io.ReadSeeker
 	io.ReaderAt
+	// ReadAtContext is ReadAt with a context.
+	ReadAtContext(ctx context.Context, p []byte, off int64) (int, error)
 	// ResultSet returns the upstream result set.
 	ResultSet() resultset.ResultSet
 }
@@ -53,9 +65,14 @@ type CachedReader struct {
 var _ io.ReadSeeker = new(CachedReader)
 var _ io.ReaderAt = new(CachedReader)
 
-// NewCachedReader returns a new CachedReader for upstream rd.
-func NewCachedReader(ctx context.Context, rd UpstreamReader) *CachedReader {
-	cache := &_cache[radixes[rd.ResultSet().Radix()]]
+// NewCachedReader returns a new CachedReader for upstream rd caching in c.
+func NewCachedReader(ctx context.Context, rd UpstreamReader, c *Cache) *CachedReader {
+	var key *ycd.YCDFile
+	if set := rd.ResultSet(); len(set) > 0 {
+		key = set[0]
+	}
+	v, _ := c.caches.LoadOrStore(key, new(cache))
+	cache := v.(*cache)
 
 	cache.once.Do(func() {
 		cache.cache = make([]byte, 0, cacheSize)
@@ -71,6 +88,11 @@ func NewCachedReader(ctx context.Context, rd UpstreamReader) *CachedReader {
 
 // ReadAt reads len(p) bytes of packed results from offset off.
 func (r *CachedReader) ReadAt(p []byte, off int64) (int, error) {
+	return r.ReadAtContext(r.ctx, p, off)
+}
+
+// ReadAtContext is the same as ReadAt but uses ctx for reads from the upstream.
+func (r *CachedReader) ReadAtContext(ctx context.Context, p []byte, off int64) (int, error) {
 	n := 0
 	if read, ok := r.readCache(p, off); ok {
 		n += read
@@ -78,7 +100,7 @@ func (r *CachedReader) ReadAt(p []byte, off int64) (int, error) {
 			return n, nil
 		}
 	}
-	read, err := r.rd.ReadAt(p[n:], off+int64(n))
+	read, err := r.rd.ReadAtContext(ctx, p[n:], off+int64(n))
 	r.updateCache(p[n:n+read], off+int64(n))
 	return n + read, err
 }
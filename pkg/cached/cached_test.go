@@ -59,7 +59,7 @@ func TestCachedReader_New(t *testing.T) {
 		t.Fatal("NewReader(): got nil, want non-nil")
 	}
 
-	rd := NewCachedReader(ctx, ur)
+	rd := NewCachedReader(ctx, ur, NewCache())
 	if rd == nil {
 		t.Errorf("NewCacheReader(): got nil, want non-nil")
 	}
@@ -122,7 +122,7 @@ func TestCachedReader_Cached(t *testing.T) {
 			t.Errorf("Close() failed: %v", err)
 		}
 	})
-	rd := NewCachedReader(ctx, ur)
+	rd := NewCachedReader(ctx, ur, NewCache())
 
 	testCases := []struct {
 		off int64
@@ -154,7 +154,6 @@ func TestCachedReader_Cached(t *testing.T) {
 func TestCacheReader_Simple(t *testing.T) {
 	t.Parallel()
 
-	mockCtrl := gomock.NewController(t)
 	testSet := resultset.ResultSet{
 		{
 			Header: &ycd.Header{
@@ -170,7 +169,7 @@ func TestCacheReader_Simple(t *testing.T) {
 	}
 	ctx := context.Background()
 	testBuf := tests.GenTestByteSeq(int(testSet.TotalByteLength()))
-	bucket := tests.NewMockBucket(ctx, mockCtrl, testSet, testBuf)
+	bucket := tests.NewTestBucket(testSet, testBuf)
 
 	ur := testSet.NewReader(ctx, bucket)
 	t.Cleanup(func() {
@@ -179,7 +178,7 @@ func TestCacheReader_Simple(t *testing.T) {
 		}
 	})
 
-	rd := NewCachedReader(ctx, ur)
+	rd := NewCachedReader(ctx, ur, NewCache())
 	testCases := []struct {
 		off int64
 		n   int
@@ -282,9 +281,8 @@ func TestCachedReader_IOTest(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 			ctx := context.Background()
-			mockCtrl := gomock.NewController(t)
 			testBuf := tests.GenTestByteSeq(int(tc.set.TotalByteLength()))
-			bucket := tests.NewMockBucket(ctx, mockCtrl, tc.set, testBuf)
+			bucket := tests.NewTestBucket(tc.set, testBuf)
 
 			ur := tc.set.NewReader(ctx, bucket)
 			t.Cleanup(func() {
@@ -292,7 +290,7 @@ func TestCachedReader_IOTest(t *testing.T) {
 					t.Errorf("Close() failed: %v", err)
 				}
 			})
-			rd := NewCachedReader(ctx, ur)
+			rd := NewCachedReader(ctx, ur, NewCache())
 			if err := iotest.TestReader(rd, testBuf); err != nil {
 				t.Errorf("TestReader() failed: %v", err)
 			}
@@ -0,0 +1,334 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package integrity verifies YCD objects in a bucket end to end.
+package integrity
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"sync"
+
+	"github.com/googlecloudplatform/pi-delivery/pkg/obj"
+	"github.com/googlecloudplatform/pi-delivery/pkg/resultset"
+	"github.com/googlecloudplatform/pi-delivery/pkg/unpack"
+	"github.com/googlecloudplatform/pi-delivery/pkg/ycd"
+)
+
+// Kinds of problems found in a block.
+const (
+	// KindMissing means the object doesn't exist.
+	KindMissing = "missing"
+	// KindHeader means the header doesn't parse or doesn't match the result set.
+	KindHeader = "header"
+	// KindLength means the object is shorter or longer than expected.
+	KindLength = "length"
+	// KindWord means a word can't be unpacked.
+	KindWord = "word"
+	// KindChecksum means the object doesn't match its stored checksum.
+	KindChecksum = "checksum"
+)
+
+// DefaultMaxProblems is the default number of problems recorded per block.
+const DefaultMaxProblems = 100
+
+const bufferSize = 1 << 20
+
+// Problem is a problem found in a block.
+type Problem struct {
+	Kind string `json:"kind"`
+	// ByteOffset is the byte offset of the problem in the object.
+	ByteOffset int64 `json:"byteOffset"`
+	// DigitOffset is the offset of the first digit of the bad word in the
+	// result set, counting the first digit after the point as 0.
+	// It's only set for KindWord.
+	DigitOffset int64  `json:"digitOffset,omitempty"`
+	Message     string `json:"message"`
+}
+
+// BlockReport is the result of verifying a block.
+type BlockReport struct {
+	BlockID int64  `json:"blockID"`
+	Name    string `json:"name"`
+	// Size is the number of bytes read from the object.
+	Size int64 `json:"size"`
+	// ExpectedSize is the size of the object computed from its header.
+	ExpectedSize int64 `json:"expectedSize"`
+	// CRC32C is the checksum of the bytes read in base64 as Cloud Storage reports it.
+	CRC32C string `json:"crc32c"`
+	// StoredCRC32C is the stored checksum of the object if available.
+	StoredCRC32C string `json:"storedCRC32C,omitempty"`
+	// InvalidWords is the number of words that can't be unpacked.
+	InvalidWords int64 `json:"invalidWords"`
+	// Problems are the problems found in the block. Only the first
+	// Options.MaxProblems are recorded.
+	Problems []Problem `json:"problems,omitempty"`
+	OK       bool      `json:"ok"`
+}
+
+func (r *BlockReport) addProblem(p Problem, max int) {
+	if len(r.Problems) < max {
+		r.Problems = append(r.Problems, p)
+	}
+}
+
+// ChecksumFunc returns the stored CRC32C checksum of the object name.
+// ok is false if no checksum is stored for the object.
+type ChecksumFunc func(ctx context.Context, name string) (crc uint32, ok bool, err error)
+
+// ObjectChecksums returns a ChecksumFunc that reads the CRC32C checksums
+// in the attributes of the objects in bucket.
+// Checksums aren't available if the storage doesn't have them.
+func ObjectChecksums(bucket obj.Bucket) ChecksumFunc {
+	return func(ctx context.Context, name string) (uint32, bool, error) {
+		attrs, err := bucket.Object(name).Attrs(ctx)
+		if err != nil {
+			return 0, false, err
+		}
+		return attrs.CRC32C, attrs.HasCRC32C, nil
+	}
+}
+
+// Options are options for Verify and VerifyBlock.
+type Options struct {
+	// Checksums returns stored checksums. Checksums aren't compared if it's nil.
+	Checksums ChecksumFunc
+	// MaxProblems is the maximum number of problems recorded per block.
+	// Defaults to DefaultMaxProblems.
+	MaxProblems int
+	// Workers is the number of blocks verified concurrently. Defaults to 1.
+	Workers int
+}
+
+// EncodeCRC32C encodes crc in base64 as Cloud Storage reports it.
+func EncodeCRC32C(crc uint32) string {
+	return base64.StdEncoding.EncodeToString(binary.BigEndian.AppendUint32(nil, crc))
+}
+
+// DecodeCRC32C decodes a base64 CRC32C checksum as Cloud Storage reports it.
+func DecodeCRC32C(s string) (uint32, error) {
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return 0, err
+	}
+	if len(b) != 4 {
+		return 0, fmt.Errorf("invalid CRC32C checksum: %s", s)
+	}
+	return binary.BigEndian.Uint32(b), nil
+}
+
+// ExpectedSize returns the size of the object of f in set including the header.
+// The last block only contains words up to the total number of digits.
+func ExpectedSize(set resultset.ResultSet, f *ycd.YCDFile) int64 {
+	return ExpectedFileSize(f, set.TotalDigits())
+}
+
+// ExpectedFileSize returns the size of the object of f including the header
+// when the result set has totalDigits digits. If totalDigits is 0, as in the
+// header of a block other than the last, f is a full block.
+func ExpectedFileSize(f *ycd.YCDFile, totalDigits int64) int64 {
+	n := f.BlockByteLength()
+	remaining := totalDigits - f.Header.BlockID*f.Header.BlockSize
+	if totalDigits != 0 && remaining < f.Header.BlockSize {
+		dpw := int64(ycd.DigitsPerWord(f.Header.Radix))
+		if remaining < 0 {
+			remaining = 0
+		}
+		n = (remaining + dpw - 1) / dpw * ycd.WordSize
+	}
+	return int64(f.FirstDigitOffset) + n
+}
+
+// VerifyBlock reads the whole object of set[i] from bucket and verifies it.
+// It returns an error only if the verification itself fails,
+// e.g. the context is canceled. Problems in the object are in the report.
+func VerifyBlock(ctx context.Context, set resultset.ResultSet, bucket obj.Bucket, i int, opts *Options) (*BlockReport, error) {
+	maxProblems := DefaultMaxProblems
+	if opts != nil && opts.MaxProblems > 0 {
+		maxProblems = opts.MaxProblems
+	}
+	f := set[i]
+	r := &BlockReport{
+		BlockID:      f.Header.BlockID,
+		Name:         f.Name,
+		ExpectedSize: ExpectedSize(set, f),
+	}
+
+	rd, err := bucket.Object(f.Name).NewRangeReader(ctx, 0, -1)
+	if errors.Is(err, obj.ErrObjectNotExist) {
+		r.addProblem(Problem{Kind: KindMissing, Message: err.Error()}, maxProblems)
+		return r, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", f.Name, err)
+	}
+	defer rd.Close()
+
+	crc := crc32.New(obj.CRC32CTable)
+	tr := io.TeeReader(rd, crc)
+	if err := verifyHeader(r, f, tr, maxProblems); err != nil {
+		return nil, err
+	}
+	if err := verifyWords(ctx, r, f, tr, maxProblems); err != nil {
+		return nil, err
+	}
+	if r.Size != r.ExpectedSize {
+		r.addProblem(Problem{
+			Kind:       KindLength,
+			ByteOffset: r.Size,
+			Message:    fmt.Sprintf("size = %d, want %d", r.Size, r.ExpectedSize),
+		}, maxProblems)
+	}
+
+	r.CRC32C = EncodeCRC32C(crc.Sum32())
+	if opts != nil && opts.Checksums != nil {
+		stored, ok, err := opts.Checksums(ctx, f.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the checksum of %s: %w", f.Name, err)
+		}
+		if ok {
+			r.StoredCRC32C = EncodeCRC32C(stored)
+			if stored != crc.Sum32() {
+				r.addProblem(Problem{
+					Kind:    KindChecksum,
+					Message: fmt.Sprintf("crc32c = %s, stored %s", r.CRC32C, r.StoredCRC32C),
+				}, maxProblems)
+			}
+		}
+	}
+	r.OK = len(r.Problems) == 0 && r.InvalidWords == 0
+	return r, nil
+}
+
+// verifyHeader reads the header of f from rd and checks it matches f.
+// A truncated header is left to the size check.
+func verifyHeader(r *BlockReport, f *ycd.YCDFile, rd io.Reader, maxProblems int) error {
+	buf := make([]byte, f.FirstDigitOffset)
+	n, err := io.ReadFull(rd, buf)
+	r.Size += int64(n)
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to read %s: %w", f.Name, err)
+	}
+
+	got, err := ycd.Parse(bytes.NewReader(buf))
+	if err != nil {
+		r.addProblem(Problem{Kind: KindHeader, Message: err.Error()}, maxProblems)
+		return nil
+	}
+	var diffs []string
+	check := func(name string, got, want int64) {
+		if got != want {
+			diffs = append(diffs, fmt.Sprintf("%s = %d, want %d", name, got, want))
+		}
+	}
+	check("Radix", int64(got.Header.Radix), int64(f.Header.Radix))
+	check("BlockSize", got.Header.BlockSize, f.Header.BlockSize)
+	check("BlockID", got.Header.BlockID, f.Header.BlockID)
+	check("TotalDigits", got.Header.TotalDigits, f.Header.TotalDigits)
+	check("FirstDigitOffset", int64(got.FirstDigitOffset), int64(f.FirstDigitOffset))
+	for _, d := range diffs {
+		r.addProblem(Problem{Kind: KindHeader, Message: d}, maxProblems)
+	}
+	return nil
+}
+
+// verifyWords reads the digits of f from rd and checks each word can be unpacked.
+func verifyWords(ctx context.Context, r *BlockReport, f *ycd.YCDFile, rd io.Reader, maxProblems int) error {
+	radix := f.Header.Radix
+	dpw := int64(ycd.DigitsPerWord(radix))
+	blockStart := f.Header.BlockID * f.Header.BlockSize
+	buf := make([]byte, bufferSize)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		n, err := io.ReadFull(rd, buf)
+		for i := 0; i+ycd.WordSize <= n; i += ycd.WordSize {
+			if !unpack.ValidWord(binary.LittleEndian.Uint64(buf[i:]), radix) {
+				r.InvalidWords++
+				off := r.Size + int64(i)
+				r.addProblem(Problem{
+					Kind:        KindWord,
+					ByteOffset:  off,
+					DigitOffset: blockStart + (off-int64(f.FirstDigitOffset))/ycd.WordSize*dpw,
+					Message:     fmt.Sprintf("%v: %x", unpack.ErrInvalidWord, buf[i:i+ycd.WordSize]),
+				}, maxProblems)
+			}
+		}
+		r.Size += int64(n)
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to read %s: %w", f.Name, err)
+		}
+	}
+}
+
+// Verify verifies every block of set in bucket and calls fn with each report.
+// fn is called from one goroutine at a time but not necessarily in the order of blocks.
+// Verify stops at the first error returned by VerifyBlock or fn.
+func Verify(ctx context.Context, set resultset.ResultSet, bucket obj.Bucket, opts *Options, fn func(*BlockReport) error) error {
+	workers := 1
+	if opts != nil && opts.Workers > 0 {
+		workers = opts.Workers
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	blocks := make(chan int)
+	go func() {
+		defer close(blocks)
+		for i := range set {
+			select {
+			case blocks <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var mu sync.Mutex
+	var firstErr error
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range blocks {
+				r, err := VerifyBlock(ctx, set, bucket, i, opts)
+				mu.Lock()
+				if err == nil && firstErr == nil {
+					err = fn(r)
+				}
+				if err != nil && firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
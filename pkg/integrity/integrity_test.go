@@ -0,0 +1,340 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package integrity
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/googlecloudplatform/pi-delivery/pkg/obj"
+	"github.com/googlecloudplatform/pi-delivery/pkg/obj/memory"
+	"github.com/googlecloudplatform/pi-delivery/pkg/resultset"
+	"github.com/googlecloudplatform/pi-delivery/pkg/ycd"
+)
+
+const (
+	testBlockSize   = 40
+	testTotalDigits = 100
+	testBlocks      = 3
+	// testWords is the number of words in a full block.
+	testWords = 3
+)
+
+// testHeader returns the header of block id as y-cruncher writes it.
+func testHeader(id int64) []byte {
+	h := fmt.Sprintf("#Compressed Digit File\n\nFileVersion:\t1.1.0\n\nBase:\t10\n\n"+
+		"FirstDigits:\t3.14159265358979323846264338327950288419716939937510\n\n"+
+		"TotalDigits:\t%d\n\nBlocksize:\t%d\nBlockID:\t%d\n\nEndHeader\n\n",
+		testTotalDigits, testBlockSize, id)
+	return append([]byte(strings.ReplaceAll(h, "\n", "\r\n")), 0)
+}
+
+// newTestObjects returns the objects of a valid result set keyed by name.
+func newTestObjects(t *testing.T) (resultset.ResultSet, map[string][]byte) {
+	t.Helper()
+	set := make(resultset.ResultSet, 0, testBlocks)
+	objects := make(map[string][]byte)
+	for id := int64(0); id < testBlocks; id++ {
+		data := testHeader(id)
+		f, err := ycd.Parse(strings.NewReader(string(data)))
+		if err != nil {
+			t.Fatalf("ycd.Parse() failed: %v", err)
+		}
+		f.Name = fmt.Sprintf("test/test - %d.ycd", id)
+		words := testWords
+		if id == testBlocks-1 {
+			// 20 digits are left in the last block.
+			words = 2
+		}
+		for w := 0; w < words; w++ {
+			data = binary.LittleEndian.AppendUint64(data, uint64(id*100+int64(w)))
+		}
+		set = append(set, f)
+		objects[f.Name] = data
+	}
+	return set, objects
+}
+
+func newTestBucket(objects map[string][]byte) *memory.Bucket {
+	bucket := memory.NewBucket()
+	for name, data := range objects {
+		bucket.Put(name, data)
+	}
+	return bucket
+}
+
+func TestExpectedSize(t *testing.T) {
+	t.Parallel()
+	set, objects := newTestObjects(t)
+	for _, f := range set {
+		if got, want := ExpectedSize(set, f), int64(len(objects[f.Name])); got != want {
+			t.Errorf("ExpectedSize(%d) = got %d, want %d", f.Header.BlockID, got, want)
+		}
+		// Each header alone is enough as only the last block has the total.
+		if got, want := ExpectedFileSize(f, f.Header.TotalDigits), int64(len(objects[f.Name])); got != want {
+			t.Errorf("ExpectedFileSize(%d) = got %d, want %d", f.Header.BlockID, got, want)
+		}
+	}
+}
+
+func TestCRC32C(t *testing.T) {
+	t.Parallel()
+	// The CRC32C of "hello" as reported by gsutil hash.
+	const want = "mnG7TA=="
+	crc := crc32.Checksum([]byte("hello"), obj.CRC32CTable)
+	if got := EncodeCRC32C(crc); got != want {
+		t.Errorf("EncodeCRC32C() = got %s, want %s", got, want)
+	}
+	got, err := DecodeCRC32C(want)
+	if err != nil {
+		t.Fatalf("DecodeCRC32C() failed: %v", err)
+	}
+	if got != crc {
+		t.Errorf("DecodeCRC32C() = got %x, want %x", got, crc)
+	}
+	if _, err := DecodeCRC32C("AAAA"); err == nil {
+		t.Errorf("DecodeCRC32C() should fail for a short checksum")
+	}
+}
+
+func TestVerifyBlock(t *testing.T) {
+	t.Parallel()
+
+	set, _ := newTestObjects(t)
+	fdo := int64(set[0].FirstDigitOffset)
+	invalid := binary.LittleEndian.AppendUint64(nil, math.MaxUint64)
+
+	testCases := []struct {
+		name      string
+		block     int
+		modify    func(data []byte) []byte
+		checksum  bool
+		wantKinds []string
+		wantWords int64
+		wantOff   []int64
+	}{
+		{
+			name:   "ok",
+			block:  1,
+			modify: func(data []byte) []byte { return data },
+		},
+		{
+			name:     "ok with checksum",
+			block:    2,
+			modify:   func(data []byte) []byte { return data },
+			checksum: true,
+		},
+		{
+			name:  "invalid word",
+			block: 1,
+			modify: func(data []byte) []byte {
+				copy(data[fdo+16:], invalid)
+				return data
+			},
+			wantKinds: []string{KindWord},
+			wantWords: 1,
+			wantOff:   []int64{fdo + 16},
+		},
+		{
+			name:      "truncated",
+			block:     0,
+			modify:    func(data []byte) []byte { return data[:len(data)-3] },
+			wantKinds: []string{KindLength},
+			wantOff:   []int64{fdo + 21},
+		},
+		{
+			name:      "truncated header",
+			block:     0,
+			modify:    func(data []byte) []byte { return data[:10] },
+			wantKinds: []string{KindLength},
+			wantOff:   []int64{10},
+		},
+		{
+			name:      "padded last block",
+			block:     2,
+			modify:    func(data []byte) []byte { return append(data, make([]byte, ycd.WordSize)...) },
+			wantKinds: []string{KindLength},
+			wantOff:   []int64{fdo + 24},
+		},
+		{
+			name:  "wrong block id",
+			block: 0,
+			modify: func(data []byte) []byte {
+				copy(data, testHeader(1))
+				return data
+			},
+			wantKinds: []string{KindHeader},
+			wantOff:   []int64{0},
+		},
+		{
+			name:      "missing",
+			block:     0,
+			modify:    func(data []byte) []byte { return nil },
+			wantKinds: []string{KindMissing},
+			wantOff:   []int64{0},
+		},
+		{
+			name:  "checksum",
+			block: 0,
+			modify: func(data []byte) []byte {
+				data[fdo]++
+				return data
+			},
+			checksum:  true,
+			wantKinds: []string{KindChecksum},
+			wantOff:   []int64{0},
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			set, objects := newTestObjects(t)
+			name := set[tc.block].Name
+			crc := crc32.Checksum(objects[name], obj.CRC32CTable)
+			if data := tc.modify(objects[name]); data != nil {
+				objects[name] = data
+			} else {
+				delete(objects, name)
+			}
+			opts := &Options{}
+			if tc.checksum {
+				opts.Checksums = func(ctx context.Context, n string) (uint32, bool, error) {
+					return crc, n == name, nil
+				}
+			}
+
+			r, err := VerifyBlock(context.Background(), set, newTestBucket(objects), tc.block, opts)
+			if err != nil {
+				t.Fatalf("VerifyBlock() failed: %v", err)
+			}
+			var kinds []string
+			var offs []int64
+			for _, p := range r.Problems {
+				kinds = append(kinds, p.Kind)
+				offs = append(offs, p.ByteOffset)
+			}
+			if diff := cmp.Diff(tc.wantKinds, kinds); diff != "" {
+				t.Errorf("VerifyBlock() problems = (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantOff, offs); diff != "" {
+				t.Errorf("VerifyBlock() offsets = (-want, +got):\n%s", diff)
+			}
+			if r.InvalidWords != tc.wantWords {
+				t.Errorf("VerifyBlock(): InvalidWords = got %d, want %d", r.InvalidWords, tc.wantWords)
+			}
+			if r.OK != (len(tc.wantKinds) == 0) {
+				t.Errorf("VerifyBlock(): OK = got %v, want %v", r.OK, len(tc.wantKinds) == 0)
+			}
+			if tc.checksum != (r.StoredCRC32C != "") {
+				t.Errorf("VerifyBlock(): StoredCRC32C = %q", r.StoredCRC32C)
+			}
+			if _, ok := objects[name]; !ok {
+				return
+			}
+			if got, want := r.CRC32C, EncodeCRC32C(crc32.Checksum(objects[name], obj.CRC32CTable)); got != want {
+				t.Errorf("VerifyBlock(): CRC32C = got %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestVerifyBlock_DigitOffset(t *testing.T) {
+	t.Parallel()
+
+	set, objects := newTestObjects(t)
+	data := objects[set[1].Name]
+	for i := set[1].FirstDigitOffset; i < len(data); i++ {
+		data[i] = 0xff
+	}
+	r, err := VerifyBlock(context.Background(), set, newTestBucket(objects), 1, &Options{MaxProblems: 2})
+	if err != nil {
+		t.Fatalf("VerifyBlock() failed: %v", err)
+	}
+	if r.InvalidWords != testWords {
+		t.Errorf("VerifyBlock(): InvalidWords = got %d, want %d", r.InvalidWords, testWords)
+	}
+	var offs []int64
+	for _, p := range r.Problems {
+		offs = append(offs, p.DigitOffset)
+	}
+	if diff := cmp.Diff([]int64{40, 59}, offs); diff != "" {
+		t.Errorf("VerifyBlock() digit offsets = (-want, +got):\n%s", diff)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	t.Parallel()
+
+	set, objects := newTestObjects(t)
+	objects[set[2].Name] = objects[set[2].Name][:10]
+	bucket := newTestBucket(objects)
+
+	var mu sync.Mutex
+	var got []int64
+	var bad []int64
+	err := Verify(context.Background(), set, bucket, &Options{Workers: 2}, func(r *BlockReport) error {
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, r.BlockID)
+		if !r.OK {
+			bad = append(bad, r.BlockID)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Verify() failed: %v", err)
+	}
+	sort.Slice(got, func(i, j int) bool { return got[i] < got[j] })
+	if diff := cmp.Diff([]int64{0, 1, 2}, got); diff != "" {
+		t.Errorf("Verify() blocks = (-want, +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]int64{2}, bad); diff != "" {
+		t.Errorf("Verify() bad blocks = (-want, +got):\n%s", diff)
+	}
+
+	errStop := errors.New("stop")
+	err = Verify(context.Background(), set, bucket, nil, func(r *BlockReport) error {
+		return errStop
+	})
+	if !cmp.Equal(err, errStop, cmpopts.EquateErrors()) {
+		t.Errorf("Verify() = got error %v, want %v", err, errStop)
+	}
+}
+
+func TestObjectChecksums(t *testing.T) {
+	t.Parallel()
+
+	set, objects := newTestObjects(t)
+	bucket := newTestBucket(objects)
+	opts := &Options{Checksums: ObjectChecksums(bucket)}
+	r, err := VerifyBlock(context.Background(), set, bucket, 0, opts)
+	if err != nil {
+		t.Fatalf("VerifyBlock() failed: %v", err)
+	}
+	if !r.OK || r.StoredCRC32C != r.CRC32C {
+		t.Errorf("VerifyBlock() = got OK %v, crc32c %s, stored %s", r.OK, r.CRC32C, r.StoredCRC32C)
+	}
+}
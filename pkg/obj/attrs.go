@@ -0,0 +1,136 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package obj
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+)
+
+// ErrChecksumMismatch is returned when the data read from an object doesn't match
+// its size or checksums.
+var ErrChecksumMismatch = errors.New("obj: checksum mismatch")
+
+// CRC32CTable is the table of the CRC32C checksums in ObjectAttrs.
+var CRC32CTable = crc32.MakeTable(crc32.Castagnoli)
+
+// ObjectAttrs are attributes of an object.
+type ObjectAttrs struct {
+	// Size is the length of the object in bytes.
+	Size int64
+	// CRC32C is the CRC32C checksum of the object if HasCRC32C is true.
+	CRC32C uint32
+	// HasCRC32C is true if CRC32C is set.
+	HasCRC32C bool
+	// MD5 is the MD5 hash of the object. It's nil if the storage doesn't have it,
+	// e.g. for composite objects in Cloud Storage.
+	MD5 []byte
+	// Generation is the generation of the object content.
+	Generation int64
+}
+
+// ValidatingObject is an Object that implements NewValidatingReader itself,
+// e.g. to read the same generation as the attributes.
+type ValidatingObject interface {
+	Object
+	// NewValidatingReader is the same as the package-level NewValidatingReader.
+	NewValidatingReader(ctx context.Context) (io.ReadCloser, error)
+}
+
+// NewValidatingReader returns a reader of the whole object o that checks the
+// data against the size and checksums in its attributes.
+// The reader returns an error wrapping ErrChecksumMismatch instead of io.EOF
+// if they don't match. Only the size is checked if the storage doesn't have checksums.
+func NewValidatingReader(ctx context.Context, o Object) (io.ReadCloser, error) {
+	if vo, ok := o.(ValidatingObject); ok {
+		return vo.NewValidatingReader(ctx)
+	}
+	attrs, err := o.Attrs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rd, err := o.NewRangeReader(ctx, 0, -1)
+	if err != nil {
+		return nil, err
+	}
+	return NewChecksumReader(rd, attrs), nil
+}
+
+// NewChecksumReader wraps rd, a reader of the whole object described by attrs,
+// so that it returns an error wrapping ErrChecksumMismatch instead of io.EOF
+// if the data doesn't match the size or checksums in attrs.
+func NewChecksumReader(rd io.ReadCloser, attrs *ObjectAttrs) io.ReadCloser {
+	r := &checksumReader{rd: rd, attrs: attrs}
+	if attrs.HasCRC32C {
+		r.crc = crc32.New(CRC32CTable)
+	}
+	if attrs.MD5 != nil {
+		r.md5 = md5.New()
+	}
+	return r
+}
+
+type checksumReader struct {
+	rd    io.ReadCloser
+	attrs *ObjectAttrs
+	n     int64
+	crc   hash.Hash32
+	md5   hash.Hash
+}
+
+func (r *checksumReader) Read(p []byte) (int, error) {
+	n, err := r.rd.Read(p)
+	r.n += int64(n)
+	if r.crc != nil {
+		r.crc.Write(p[:n])
+	}
+	if r.md5 != nil {
+		r.md5.Write(p[:n])
+	}
+	if r.n > r.attrs.Size {
+		return n, fmt.Errorf("%w: read more than %d bytes", ErrChecksumMismatch, r.attrs.Size)
+	}
+	if err == io.EOF {
+		if verr := r.validate(); verr != nil {
+			return n, verr
+		}
+	}
+	return n, err
+}
+
+func (r *checksumReader) validate() error {
+	if r.n != r.attrs.Size {
+		return fmt.Errorf("%w: size = %d, want %d", ErrChecksumMismatch, r.n, r.attrs.Size)
+	}
+	if r.crc != nil && r.crc.Sum32() != r.attrs.CRC32C {
+		return fmt.Errorf("%w: crc32c = %08x, want %08x", ErrChecksumMismatch, r.crc.Sum32(), r.attrs.CRC32C)
+	}
+	if r.md5 != nil {
+		if sum := r.md5.Sum(nil); !bytes.Equal(sum, r.attrs.MD5) {
+			return fmt.Errorf("%w: md5 = %x, want %x", ErrChecksumMismatch, sum, r.attrs.MD5)
+		}
+	}
+	return nil
+}
+
+func (r *checksumReader) Close() error {
+	return r.rd.Close()
+}
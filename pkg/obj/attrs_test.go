@@ -0,0 +1,98 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package obj_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googlecloudplatform/pi-delivery/pkg/obj"
+	"github.com/googlecloudplatform/pi-delivery/pkg/obj/memory"
+)
+
+func TestNewChecksumReader(t *testing.T) {
+	t.Parallel()
+
+	data := []byte("hello")
+	good := obj.ObjectAttrs{
+		Size:      5,
+		CRC32C:    0x9a71bb4c,
+		HasCRC32C: true,
+		MD5: []byte{0x5d, 0x41, 0x40, 0x2a, 0xbc, 0x4b, 0x2a, 0x76,
+			0xb9, 0x71, 0x9d, 0x91, 0x10, 0x17, 0xc5, 0x92},
+	}
+
+	testCases := []struct {
+		name    string
+		modify  func(a *obj.ObjectAttrs)
+		wantErr error
+	}{
+		{"ok", func(a *obj.ObjectAttrs) {}, nil},
+		{"no checksums", func(a *obj.ObjectAttrs) { a.HasCRC32C, a.CRC32C, a.MD5 = false, 0, nil }, nil},
+		{"crc32c", func(a *obj.ObjectAttrs) { a.CRC32C++ }, obj.ErrChecksumMismatch},
+		{"md5", func(a *obj.ObjectAttrs) { a.MD5 = make([]byte, 16) }, obj.ErrChecksumMismatch},
+		{"short", func(a *obj.ObjectAttrs) { a.Size = 4 }, obj.ErrChecksumMismatch},
+		{"long", func(a *obj.ObjectAttrs) { a.Size = 6 }, obj.ErrChecksumMismatch},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			attrs := good
+			tc.modify(&attrs)
+			rd := obj.NewChecksumReader(io.NopCloser(iotest.OneByteReader(bytes.NewReader(data))), &attrs)
+			defer rd.Close()
+			got, err := io.ReadAll(rd)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("ReadAll() error = got %v, want %v", err, tc.wantErr)
+			}
+			if err == nil {
+				if diff := cmp.Diff(data, got); diff != "" {
+					t.Errorf("ReadAll() = (-want, +got):\n%s", diff)
+				}
+			}
+		})
+	}
+}
+
+func TestNewValidatingReader(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	bucket := memory.NewBucket()
+	bucket.Put("object", []byte("hello"))
+
+	rd, err := obj.NewValidatingReader(ctx, bucket.Object("object"))
+	if err != nil {
+		t.Fatalf("NewValidatingReader() failed: %v", err)
+	}
+	defer rd.Close()
+	got, err := io.ReadAll(rd)
+	if err != nil {
+		t.Fatalf("ReadAll() failed: %v", err)
+	}
+	if string(got) != "hello" {
+		t.Errorf("ReadAll() = got %q, want %q", got, "hello")
+	}
+
+	if _, err := obj.NewValidatingReader(ctx, bucket.Object("missing")); !errors.Is(err, obj.ErrObjectNotExist) {
+		t.Errorf("NewValidatingReader() error = got %v, want %v", err, obj.ErrObjectNotExist)
+	}
+}
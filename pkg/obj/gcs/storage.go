@@ -16,10 +16,15 @@ package gcs
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io"
+	"net/http"
 
 	"cloud.google.com/go/storage"
 	"github.com/googlecloudplatform/pi-delivery/pkg/obj"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
 )
 
@@ -37,6 +42,8 @@ type Object struct {
 	h *storage.ObjectHandle
 }
 
+var _ obj.ValidatingObject = new(Object)
+
 // NewClient returns a new client object for Google Cloud Storage.
 func NewClient(ctx context.Context, ops ...option.ClientOption) (obj.Client, error) {
 	client, err := storage.NewClient(ctx, ops...)
@@ -58,6 +65,83 @@ func (b *Bucket) Object(name string) obj.Object {
 	return &Object{h: b.h.Object(name)}
 }
 
+// Objects returns an iterator over the objects matching q.
+func (b *Bucket) Objects(ctx context.Context, q *obj.Query) obj.ObjectIterator {
+	query := &storage.Query{}
+	if q != nil {
+		query.Prefix = q.Prefix
+		query.Delimiter = q.Delimiter
+	}
+	if err := query.SetAttrSelection([]string{"Name"}); err != nil {
+		return obj.NewErrorIterator(err)
+	}
+	return &objectIterator{it: b.h.Objects(ctx, query)}
+}
+
+// objectIterator converts storage.ObjectIterator to obj.ObjectIterator.
+type objectIterator struct {
+	it *storage.ObjectIterator
+}
+
+func (it *objectIterator) Next() (*obj.ListEntry, error) {
+	attrs, err := it.it.Next()
+	if err == iterator.Done {
+		return nil, obj.Done
+	}
+	if err != nil {
+		return nil, convertError(err)
+	}
+	return &obj.ListEntry{Name: attrs.Name, Prefix: attrs.Prefix}, nil
+}
+
 func (o *Object) NewRangeReader(ctx context.Context, offset, length int64) (io.ReadCloser, error) {
-	return o.h.NewRangeReader(ctx, offset, length)
+	rd, err := o.h.NewRangeReader(ctx, offset, length)
+	return rd, convertError(err)
+}
+
+// Attrs returns the attributes of the object.
+func (o *Object) Attrs(ctx context.Context) (*obj.ObjectAttrs, error) {
+	attrs, err := o.h.Attrs(ctx)
+	if err != nil {
+		return nil, convertError(err)
+	}
+	a := &obj.ObjectAttrs{
+		Size:       attrs.Size,
+		CRC32C:     attrs.CRC32C,
+		HasCRC32C:  true,
+		Generation: attrs.Generation,
+	}
+	// Composite objects don't have MD5 hashes.
+	if len(attrs.MD5) > 0 {
+		a.MD5 = attrs.MD5
+	}
+	return a, nil
+}
+
+// NewValidatingReader returns a reader of the whole object that fails with
+// obj.ErrChecksumMismatch if the data doesn't match the attributes.
+// It reads the generation the attributes were fetched for so that an overwrite
+// during the read fails instead of mixing generations.
+func (o *Object) NewValidatingReader(ctx context.Context) (io.ReadCloser, error) {
+	attrs, err := o.Attrs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rd, err := o.h.Generation(attrs.Generation).NewReader(ctx)
+	if err != nil {
+		return nil, convertError(err)
+	}
+	return obj.NewChecksumReader(rd, attrs), nil
+}
+
+// convertError converts Cloud Storage errors to the errors defined in obj.
+func convertError(err error) error {
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return fmt.Errorf("%w: %v", obj.ErrObjectNotExist, err)
+	}
+	var e *googleapi.Error
+	if errors.As(err, &e) && e.Code == http.StatusRequestedRangeNotSatisfiable {
+		return fmt.Errorf("%w: %v", obj.ErrInvalidRange, err)
+	}
+	return err
 }
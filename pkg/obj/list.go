@@ -0,0 +1,93 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package obj
+
+import (
+	"context"
+	"sort"
+	"strings"
+)
+
+// NewNameIterator returns an ObjectIterator over the entries of names matching q.
+// It's meant for storage that lists objects by collecting all of their names.
+func NewNameIterator(names []string, q *Query) ObjectIterator {
+	if q == nil {
+		q = &Query{}
+	}
+	sorted := make([]string, 0, len(names))
+	for _, name := range names {
+		if strings.HasPrefix(name, q.Prefix) {
+			sorted = append(sorted, name)
+		}
+	}
+	sort.Strings(sorted)
+
+	it := &sliceIterator{}
+	for _, name := range sorted {
+		if q.Delimiter != "" {
+			rest := name[len(q.Prefix):]
+			if i := strings.Index(rest, q.Delimiter); i >= 0 {
+				prefix := q.Prefix + rest[:i+len(q.Delimiter)]
+				// Names sharing a prefix are adjacent after sorting.
+				if n := len(it.entries); n == 0 || it.entries[n-1].Prefix != prefix {
+					it.entries = append(it.entries, ListEntry{Prefix: prefix})
+				}
+				continue
+			}
+		}
+		it.entries = append(it.entries, ListEntry{Name: name})
+	}
+	return it
+}
+
+// NewErrorIterator returns an ObjectIterator that always returns err.
+func NewErrorIterator(err error) ObjectIterator {
+	return &sliceIterator{err: err}
+}
+
+type sliceIterator struct {
+	entries []ListEntry
+	err     error
+}
+
+func (it *sliceIterator) Next() (*ListEntry, error) {
+	if it.err != nil {
+		return nil, it.err
+	}
+	if len(it.entries) == 0 {
+		return nil, Done
+	}
+	e := it.entries[0]
+	it.entries = it.entries[1:]
+	return &e, nil
+}
+
+// ListNames returns the names of all the objects matching q in bucket.
+func ListNames(ctx context.Context, bucket Bucket, q *Query) ([]string, error) {
+	it := bucket.Objects(ctx, q)
+	names := []string{}
+	for {
+		e, err := it.Next()
+		if err == Done {
+			return names, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if e.Name != "" {
+			names = append(names, e.Name)
+		}
+	}
+}
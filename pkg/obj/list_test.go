@@ -0,0 +1,110 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package obj_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googlecloudplatform/pi-delivery/pkg/obj"
+	"github.com/googlecloudplatform/pi-delivery/pkg/obj/memory"
+)
+
+func collect(t *testing.T, it obj.ObjectIterator) []obj.ListEntry {
+	t.Helper()
+	entries := []obj.ListEntry{}
+	for {
+		e, err := it.Next()
+		if err == obj.Done {
+			return entries
+		}
+		if err != nil {
+			t.Fatalf("Next() failed: %v", err)
+		}
+		entries = append(entries, *e)
+	}
+}
+
+func TestNewNameIterator(t *testing.T) {
+	t.Parallel()
+
+	names := []string{"b/2", "a-x", "a/1", "b/1", "a/c/3", "c"}
+	testCases := []struct {
+		name string
+		q    *obj.Query
+		want []obj.ListEntry
+	}{
+		{"all", nil, []obj.ListEntry{
+			{Name: "a-x"}, {Name: "a/1"}, {Name: "a/c/3"}, {Name: "b/1"}, {Name: "b/2"}, {Name: "c"},
+		}},
+		{"prefix", &obj.Query{Prefix: "a/"}, []obj.ListEntry{
+			{Name: "a/1"}, {Name: "a/c/3"},
+		}},
+		{"delimiter", &obj.Query{Delimiter: "/"}, []obj.ListEntry{
+			{Name: "a-x"}, {Prefix: "a/"}, {Prefix: "b/"}, {Name: "c"},
+		}},
+		{"prefix and delimiter", &obj.Query{Prefix: "a/", Delimiter: "/"}, []obj.ListEntry{
+			{Name: "a/1"}, {Prefix: "a/c/"},
+		}},
+		{"no match", &obj.Query{Prefix: "d"}, []obj.ListEntry{}},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			got := collect(t, obj.NewNameIterator(names, tc.q))
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("NewNameIterator() = (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestListNames(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	bucket := memory.NewBucket()
+	for _, name := range []string{"dir/b", "dir/a", "dir/sub/c", "other"} {
+		bucket.Put(name, nil)
+	}
+	got, err := obj.ListNames(ctx, bucket, &obj.Query{Prefix: "dir/", Delimiter: "/"})
+	if err != nil {
+		t.Fatalf("ListNames() failed: %v", err)
+	}
+	if diff := cmp.Diff([]string{"dir/a", "dir/b"}, got); diff != "" {
+		t.Errorf("ListNames() = (-want, +got):\n%s", diff)
+	}
+
+	errTest := errors.New("test")
+	if _, err := obj.ListNames(ctx, errorBucket{errTest}, nil); !errors.Is(err, errTest) {
+		t.Errorf("ListNames() error = got %v, want %v", err, errTest)
+	}
+}
+
+// errorBucket is a Bucket whose listing fails with err.
+type errorBucket struct {
+	err error
+}
+
+func (b errorBucket) Object(name string) obj.Object {
+	return nil
+}
+
+func (b errorBucket) Objects(ctx context.Context, q *obj.Query) obj.ObjectIterator {
+	return obj.NewErrorIterator(b.err)
+}
@@ -0,0 +1,220 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package local
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/googlecloudplatform/pi-delivery/pkg/obj"
+)
+
+// Implementations for the local file system.
+// Buckets are directories under the root directory and objects are files
+// in the bucket directories. Object names may contain slashes and they are
+// treated as subdirectories. Names that refer outside of the root directory,
+// e.g. absolute paths or paths with "..", are rejected with ErrInvalidName.
+
+// ErrInvalidName is returned when a bucket or object name refers outside of its parent directory.
+var ErrInvalidName = errors.New("local: invalid name")
+
+type Client struct {
+	root string
+}
+
+type Bucket struct {
+	dir string
+	err error
+}
+
+type Object struct {
+	path string
+	err  error
+}
+
+// localPath returns the path of the slash-separated name under dir.
+// It fails if name isn't local to dir after cleaning it.
+func localPath(dir, name string) (string, error) {
+	p := filepath.FromSlash(name)
+	if !filepath.IsLocal(p) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidName, name)
+	}
+	return filepath.Join(dir, p), nil
+}
+
+// NewClient returns a new client object for the local file system.
+// Buckets are looked up as subdirectories of root.
+func NewClient(root string) (obj.Client, error) {
+	fi, err := os.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", root)
+	}
+	return &Client{root: root}, nil
+}
+
+// Bucket returns the bucket in the subdirectory name of the root directory.
+// An empty name is the root directory itself.
+func (c *Client) Bucket(name string) obj.Bucket {
+	if name == "" {
+		return &Bucket{dir: c.root}
+	}
+	dir, err := localPath(c.root, name)
+	return &Bucket{dir: dir, err: err}
+}
+
+func (c *Client) Close() error {
+	return nil
+}
+
+func (b *Bucket) Object(name string) obj.Object {
+	if b.err != nil {
+		return &Object{err: b.err}
+	}
+	path, err := localPath(b.dir, name)
+	return &Object{path: path, err: err}
+}
+
+// Objects returns an iterator over the files matching q.
+// Object names are the slash-separated paths of the files relative to the bucket directory.
+func (b *Bucket) Objects(ctx context.Context, q *obj.Query) obj.ObjectIterator {
+	if err := ctx.Err(); err != nil {
+		return obj.NewErrorIterator(err)
+	}
+	if b.err != nil {
+		return obj.NewErrorIterator(b.err)
+	}
+	// Only walk the deepest directory containing the prefix.
+	root := ""
+	if q != nil {
+		if i := strings.LastIndex(q.Prefix, "/"); i >= 0 {
+			root = q.Prefix[:i]
+		}
+	}
+	if root != "" && !filepath.IsLocal(filepath.FromSlash(root)) {
+		// Listed names never leave the bucket directory.
+		return obj.NewNameIterator(nil, q)
+	}
+	names := []string{}
+	err := filepath.WalkDir(filepath.Join(b.dir, filepath.FromSlash(root)), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(b.dir, path)
+		if err != nil {
+			return err
+		}
+		names = append(names, filepath.ToSlash(rel))
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) && root != "" {
+		// No objects have the prefix.
+		return obj.NewNameIterator(nil, q)
+	} else if err != nil {
+		return obj.NewErrorIterator(err)
+	}
+	return obj.NewNameIterator(names, q)
+}
+
+// Attrs returns the size of the file and its modification time in nanoseconds
+// as the generation. Checksums aren't available.
+func (o *Object) Attrs(ctx context.Context) (*obj.ObjectAttrs, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if o.err != nil {
+		return nil, o.err
+	}
+	fi, err := os.Stat(o.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %v", obj.ErrObjectNotExist, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if fi.IsDir() {
+		return nil, fmt.Errorf("%w: %s is a directory", obj.ErrObjectNotExist, o.path)
+	}
+	return &obj.ObjectAttrs{
+		Size:       fi.Size(),
+		Generation: fi.ModTime().UnixNano(),
+	}, nil
+}
+
+// NewRangeReader returns a new io.ReadCloser for the section [offset, offset+length)
+// of the file. If length is negative, it reads until the end of the file.
+// If offset is negative, it's relative to the end of the file.
+func (o *Object) NewRangeReader(ctx context.Context, offset, length int64) (io.ReadCloser, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if o.err != nil {
+		return nil, o.err
+	}
+	f, err := os.Open(o.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %v", obj.ErrObjectNotExist, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	offset, length, err = obj.ResolveRange(offset, length, fi.Size())
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%w: %s", err, o.path)
+	}
+	return &reader{
+		ctx: ctx,
+		f:   f,
+		rd:  io.NewSectionReader(f, offset, length),
+	}, nil
+}
+
+// reader reads a section of a file with os.File.ReadAt.
+type reader struct {
+	ctx context.Context
+	f   *os.File
+	rd  *io.SectionReader
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.rd.Read(p)
+}
+
+func (r *reader) Close() error {
+	return r.f.Close()
+}
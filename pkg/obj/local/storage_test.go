@@ -0,0 +1,243 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package local
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googlecloudplatform/pi-delivery/pkg/obj"
+	"github.com/googlecloudplatform/pi-delivery/pkg/tests"
+)
+
+const (
+	testBucket = "bucket"
+	testObject = "dir/object.ycd"
+)
+
+func newTestClient(t *testing.T, data []byte) obj.Client {
+	t.Helper()
+	root := t.TempDir()
+	path := filepath.Join(root, testBucket, filepath.FromSlash(testObject))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("MkdirAll() failed: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("WriteFile() failed: %v", err)
+	}
+	client, err := NewClient(root)
+	if err != nil {
+		t.Fatalf("NewClient() failed: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := client.Close(); err != nil {
+			t.Errorf("Close() failed: %v", err)
+		}
+	})
+	return client
+}
+
+func TestLocal_NewRangeReader(t *testing.T) {
+	t.Parallel()
+
+	testBuf := tests.GenTestByteSeq(100)
+	client := newTestClient(t, testBuf)
+	object := client.Bucket(testBucket).Object(testObject)
+
+	testCases := []struct {
+		off, length int64
+		want        []byte
+	}{
+		{0, 10, testBuf[:10]},
+		{0, -1, testBuf},
+		{42, 8, testBuf[42:50]},
+		{90, 20, testBuf[90:]},
+		{-10, -1, testBuf[90:]},
+		{100, -1, []byte{}},
+		{100, 0, []byte{}},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(fmt.Sprintf("off %d length %d", tc.off, tc.length), func(t *testing.T) {
+			t.Parallel()
+			rd, err := object.NewRangeReader(context.Background(), tc.off, tc.length)
+			if err != nil {
+				t.Fatalf("NewRangeReader() failed: %v", err)
+			}
+			t.Cleanup(func() {
+				if err := rd.Close(); err != nil {
+					t.Errorf("Close() failed: %v", err)
+				}
+			})
+			got, err := io.ReadAll(rd)
+			if err != nil {
+				t.Errorf("ReadAll() failed: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("ReadAll() = (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestLocal_Errors(t *testing.T) {
+	t.Parallel()
+
+	client := newTestClient(t, tests.GenTestByteSeq(100))
+	bucket := client.Bucket(testBucket)
+
+	if _, err := bucket.Object("missing.ycd").NewRangeReader(context.Background(), 0, -1); !errors.Is(err, obj.ErrObjectNotExist) {
+		t.Errorf("NewRangeReader() error = got %v, want %v", err, obj.ErrObjectNotExist)
+	}
+	for _, off := range []int64{100, 101} {
+		if _, err := bucket.Object(testObject).NewRangeReader(context.Background(), off, 1); !errors.Is(err, obj.ErrInvalidRange) {
+			t.Errorf("NewRangeReader(%d, 1) error = got %v, want %v", off, err, obj.ErrInvalidRange)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	rd, err := bucket.Object(testObject).NewRangeReader(ctx, 0, -1)
+	if err != nil {
+		t.Fatalf("NewRangeReader() failed: %v", err)
+	}
+	defer rd.Close()
+	cancel()
+	if _, err := rd.Read(make([]byte, 1)); !errors.Is(err, context.Canceled) {
+		t.Errorf("Read() error = got %v, want %v", err, context.Canceled)
+	}
+
+	if _, err := NewClient(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Errorf("NewClient() error = got nil, want non-nil")
+	}
+}
+
+func TestLocal_Objects(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	client := newTestClient(t, []byte("data"))
+	bucket := client.Bucket(testBucket)
+
+	testCases := []struct {
+		q    *obj.Query
+		want []string
+	}{
+		{nil, []string{testObject}},
+		{&obj.Query{Prefix: "dir/"}, []string{testObject}},
+		{&obj.Query{Prefix: "dir/obj"}, []string{testObject}},
+		{&obj.Query{Prefix: "dir/x"}, []string{}},
+		{&obj.Query{Prefix: "missing/"}, []string{}},
+		{&obj.Query{Delimiter: "/"}, []string{}},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(fmt.Sprintf("%+v", tc.q), func(t *testing.T) {
+			t.Parallel()
+			got, err := obj.ListNames(ctx, bucket, tc.q)
+			if err != nil {
+				t.Fatalf("ListNames() failed: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("ListNames() = (-want, +got):\n%s", diff)
+			}
+		})
+	}
+
+	if _, err := obj.ListNames(ctx, client.Bucket("missing"), nil); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("ListNames() error = got %v, want %v", err, os.ErrNotExist)
+	}
+}
+
+func TestLocal_Attrs(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	client := newTestClient(t, []byte("data"))
+	attrs, err := client.Bucket(testBucket).Object(testObject).Attrs(ctx)
+	if err != nil {
+		t.Fatalf("Attrs() failed: %v", err)
+	}
+	if attrs.Size != 4 || attrs.HasCRC32C || attrs.MD5 != nil || attrs.Generation == 0 {
+		t.Errorf("Attrs() = got %+v", attrs)
+	}
+	if _, err := client.Bucket(testBucket).Object("dir").Attrs(ctx); !errors.Is(err, obj.ErrObjectNotExist) {
+		t.Errorf("Attrs() error = got %v, want %v", err, obj.ErrObjectNotExist)
+	}
+	if _, err := client.Bucket(testBucket).Object("missing").Attrs(ctx); !errors.Is(err, obj.ErrObjectNotExist) {
+		t.Errorf("Attrs() error = got %v, want %v", err, obj.ErrObjectNotExist)
+	}
+}
+
+func TestLocal_InvalidNames(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	root := t.TempDir()
+	// A file next to the root directory must not be reachable.
+	if err := os.WriteFile(filepath.Join(filepath.Dir(root), "secret"), []byte("secret"), 0644); err != nil {
+		t.Fatalf("WriteFile() failed: %v", err)
+	}
+	client, err := NewClient(root)
+	if err != nil {
+		t.Fatalf("NewClient() failed: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(root, testBucket, "dir"), 0755); err != nil {
+		t.Fatalf("MkdirAll() failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, testBucket, "object"), []byte("data"), 0644); err != nil {
+		t.Fatalf("WriteFile() failed: %v", err)
+	}
+
+	for _, tc := range []struct {
+		bucket, object string
+	}{
+		{testBucket, "../../secret"},
+		{testBucket, "dir/../../../secret"},
+		{testBucket, "/etc/passwd"},
+		{testBucket, ""},
+		{"..", "secret"},
+		{"../" + filepath.Base(root), testBucket + "/object"},
+		{"/", "etc/passwd"},
+	} {
+		object := client.Bucket(tc.bucket).Object(tc.object)
+		if _, err := object.NewRangeReader(ctx, 0, -1); !errors.Is(err, ErrInvalidName) {
+			t.Errorf("NewRangeReader(%q, %q) error = got %v, want %v", tc.bucket, tc.object, err, ErrInvalidName)
+		}
+		if _, err := object.Attrs(ctx); !errors.Is(err, ErrInvalidName) {
+			t.Errorf("Attrs(%q, %q) error = got %v, want %v", tc.bucket, tc.object, err, ErrInvalidName)
+		}
+	}
+
+	// Names that stay inside the bucket after cleaning are fine.
+	if _, err := client.Bucket(testBucket).Object("dir/../object").Attrs(ctx); err != nil {
+		t.Errorf("Attrs() failed: %v", err)
+	}
+	if _, err := obj.ListNames(ctx, client.Bucket(".."), nil); !errors.Is(err, ErrInvalidName) {
+		t.Errorf("ListNames() error = got %v, want %v", err, ErrInvalidName)
+	}
+	got, err := obj.ListNames(ctx, client.Bucket(testBucket), &obj.Query{Prefix: "../"})
+	if err != nil {
+		t.Fatalf("ListNames() failed: %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("ListNames() = got %v, want none", got)
+	}
+}
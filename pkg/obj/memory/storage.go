@@ -0,0 +1,163 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"sync"
+
+	"github.com/googlecloudplatform/pi-delivery/pkg/obj"
+)
+
+// Implementations for in-memory object storage.
+// Objects are byte slices kept in maps and the range semantics follow
+// Cloud Storage. It's meant for tests and small datasets.
+
+type Client struct {
+	lock    sync.Mutex
+	buckets map[string]*Bucket
+}
+
+type Bucket struct {
+	lock        sync.RWMutex
+	objects     map[string][]byte
+	generations map[string]int64
+	generation  int64
+}
+
+type Object struct {
+	bucket *Bucket
+	name   string
+}
+
+// NewClient returns a new empty in-memory client.
+func NewClient() *Client {
+	return &Client{buckets: make(map[string]*Bucket)}
+}
+
+// NewBucket returns a new empty in-memory bucket.
+func NewBucket() *Bucket {
+	return &Bucket{
+		objects:     make(map[string][]byte),
+		generations: make(map[string]int64),
+	}
+}
+
+// Bucket returns the bucket specified by name. It creates an empty bucket
+// if it doesn't exist yet.
+func (c *Client) Bucket(name string) obj.Bucket {
+	return c.MemoryBucket(name)
+}
+
+// MemoryBucket is the same as Bucket but returns the concrete type
+// so callers can add objects.
+func (c *Client) MemoryBucket(name string) *Bucket {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	b, ok := c.buckets[name]
+	if !ok {
+		b = NewBucket()
+		c.buckets[name] = b
+	}
+	return b
+}
+
+func (c *Client) Close() error {
+	return nil
+}
+
+// Object returns a handle to the object specified by name.
+// The object doesn't need to exist until it's read.
+func (b *Bucket) Object(name string) obj.Object {
+	return &Object{bucket: b, name: name}
+}
+
+// Objects returns an iterator over the objects matching q.
+func (b *Bucket) Objects(ctx context.Context, q *obj.Query) obj.ObjectIterator {
+	if err := ctx.Err(); err != nil {
+		return obj.NewErrorIterator(err)
+	}
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	names := make([]string, 0, len(b.objects))
+	for name := range b.objects {
+		names = append(names, name)
+	}
+	return obj.NewNameIterator(names, q)
+}
+
+// Put stores a copy of data as the object name, replacing any existing object.
+func (b *Bucket) Put(name string, data []byte) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.objects[name] = bytes.Clone(data)
+	b.generation++
+	b.generations[name] = b.generation
+}
+
+// Delete removes the object name from the bucket.
+func (b *Bucket) Delete(name string) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	delete(b.objects, name)
+	delete(b.generations, name)
+}
+
+func (b *Bucket) get(name string) ([]byte, int64, bool) {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	data, ok := b.objects[name]
+	return data, b.generations[name], ok
+}
+
+func (o *Object) NewRangeReader(ctx context.Context, offset, length int64) (io.ReadCloser, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	data, _, ok := o.bucket.get(o.name)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", obj.ErrObjectNotExist, o.name)
+	}
+	offset, length, err := obj.ResolveRange(offset, length, int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", err, o.name)
+	}
+	return io.NopCloser(bytes.NewReader(data[offset : offset+length])), nil
+}
+
+// Attrs returns the attributes of the object.
+// The checksums are computed on every call.
+func (o *Object) Attrs(ctx context.Context) (*obj.ObjectAttrs, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	data, gen, ok := o.bucket.get(o.name)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", obj.ErrObjectNotExist, o.name)
+	}
+	sum := md5.Sum(data)
+	return &obj.ObjectAttrs{
+		Size:       int64(len(data)),
+		CRC32C:     crc32.Checksum(data, obj.CRC32CTable),
+		HasCRC32C:  true,
+		MD5:        sum[:],
+		Generation: gen,
+	}, nil
+}
@@ -0,0 +1,162 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googlecloudplatform/pi-delivery/pkg/obj"
+)
+
+func TestMemory_NewRangeReader(t *testing.T) {
+	t.Parallel()
+
+	testBuf := make([]byte, 100)
+	for i := range testBuf {
+		testBuf[i] = byte(i)
+	}
+	client := NewClient()
+	client.MemoryBucket("bucket").Put("object", testBuf)
+	object := client.Bucket("bucket").Object("object")
+
+	testCases := []struct {
+		off, length int64
+		wantErr     error
+		want        []byte
+	}{
+		{0, 10, nil, testBuf[:10]},
+		{0, -1, nil, testBuf},
+		{0, 0, nil, []byte{}},
+		{42, 8, nil, testBuf[42:50]},
+		{90, 20, nil, testBuf[90:]},
+		{-10, -1, nil, testBuf[90:]},
+		{-200, -1, nil, testBuf},
+		{100, -1, nil, []byte{}},
+		{100, 1, obj.ErrInvalidRange, nil},
+		{101, -1, obj.ErrInvalidRange, nil},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(fmt.Sprintf("off %d length %d", tc.off, tc.length), func(t *testing.T) {
+			t.Parallel()
+			rd, err := object.NewRangeReader(context.Background(), tc.off, tc.length)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("NewRangeReader() error = got %v, want %v", err, tc.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			t.Cleanup(func() {
+				if err := rd.Close(); err != nil {
+					t.Errorf("Close() failed: %v", err)
+				}
+			})
+			got, err := io.ReadAll(rd)
+			if err != nil {
+				t.Errorf("ReadAll() failed: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("ReadAll() = (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestMemory_Objects(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	client := NewClient()
+	t.Cleanup(func() {
+		if err := client.Close(); err != nil {
+			t.Errorf("Close() failed: %v", err)
+		}
+	})
+	object := client.Bucket("bucket").Object("object")
+	if _, err := object.NewRangeReader(ctx, 0, -1); !errors.Is(err, obj.ErrObjectNotExist) {
+		t.Errorf("NewRangeReader() error = got %v, want %v", err, obj.ErrObjectNotExist)
+	}
+
+	data := []byte("3141592653")
+	client.MemoryBucket("bucket").Put("object", data)
+	// Put should copy the data.
+	data[0] = '0'
+	rd, err := object.NewRangeReader(ctx, 0, -1)
+	if err != nil {
+		t.Fatalf("NewRangeReader() failed: %v", err)
+	}
+	got, err := io.ReadAll(rd)
+	if err != nil {
+		t.Errorf("ReadAll() failed: %v", err)
+	}
+	if diff := cmp.Diff("3141592653", string(got)); diff != "" {
+		t.Errorf("ReadAll() = (-want, +got):\n%s", diff)
+	}
+
+	client.MemoryBucket("bucket").Delete("object")
+	if _, err := object.NewRangeReader(ctx, 0, -1); !errors.Is(err, obj.ErrObjectNotExist) {
+		t.Errorf("NewRangeReader() after Delete() error = got %v, want %v", err, obj.ErrObjectNotExist)
+	}
+
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := object.NewRangeReader(cctx, 0, -1); !errors.Is(err, context.Canceled) {
+		t.Errorf("NewRangeReader() error = got %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestMemory_Attrs(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	bucket := NewBucket()
+	bucket.Put("a", []byte("hello"))
+	bucket.Put("b", []byte("world"))
+	object := bucket.Object("a")
+
+	got, err := object.Attrs(ctx)
+	if err != nil {
+		t.Fatalf("Attrs() failed: %v", err)
+	}
+	want := &obj.ObjectAttrs{
+		Size:      5,
+		CRC32C:    0x9a71bb4c,
+		HasCRC32C: true,
+		MD5: []byte{0x5d, 0x41, 0x40, 0x2a, 0xbc, 0x4b, 0x2a, 0x76,
+			0xb9, 0x71, 0x9d, 0x91, 0x10, 0x17, 0xc5, 0x92},
+		Generation: 1,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Attrs() = (-want, +got):\n%s", diff)
+	}
+
+	bucket.Put("a", []byte("hello again"))
+	got, err = object.Attrs(ctx)
+	if err != nil {
+		t.Fatalf("Attrs() failed: %v", err)
+	}
+	if got.Generation != 3 || got.Size != 11 {
+		t.Errorf("Attrs() = got generation %d size %d, want 3 and 11", got.Generation, got.Size)
+	}
+
+	if _, err := bucket.Object("c").Attrs(ctx); !errors.Is(err, obj.ErrObjectNotExist) {
+		t.Errorf("Attrs() error = got %v, want %v", err, obj.ErrObjectNotExist)
+	}
+}
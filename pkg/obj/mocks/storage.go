@@ -101,6 +101,58 @@ func (mr *MockBucketMockRecorder) Object(name interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Object", reflect.TypeOf((*MockBucket)(nil).Object), name)
 }
 
+// Objects mocks base method.
+func (m *MockBucket) Objects(ctx context.Context, q *obj.Query) obj.ObjectIterator {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Objects", ctx, q)
+	ret0, _ := ret[0].(obj.ObjectIterator)
+	return ret0
+}
+
+// Objects indicates an expected call of Objects.
+func (mr *MockBucketMockRecorder) Objects(ctx, q interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Objects", reflect.TypeOf((*MockBucket)(nil).Objects), ctx, q)
+}
+
+// MockObjectIterator is a mock of ObjectIterator interface.
+type MockObjectIterator struct {
+	ctrl     *gomock.Controller
+	recorder *MockObjectIteratorMockRecorder
+}
+
+// MockObjectIteratorMockRecorder is the mock recorder for MockObjectIterator.
+type MockObjectIteratorMockRecorder struct {
+	mock *MockObjectIterator
+}
+
+// NewMockObjectIterator creates a new mock instance.
+func NewMockObjectIterator(ctrl *gomock.Controller) *MockObjectIterator {
+	mock := &MockObjectIterator{ctrl: ctrl}
+	mock.recorder = &MockObjectIteratorMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockObjectIterator) EXPECT() *MockObjectIteratorMockRecorder {
+	return m.recorder
+}
+
+// Next mocks base method.
+func (m *MockObjectIterator) Next() (*obj.ListEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Next")
+	ret0, _ := ret[0].(*obj.ListEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Next indicates an expected call of Next.
+func (mr *MockObjectIteratorMockRecorder) Next() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Next", reflect.TypeOf((*MockObjectIterator)(nil).Next))
+}
+
 // MockObject is a mock of Object interface.
 type MockObject struct {
 	ctrl     *gomock.Controller
@@ -124,6 +176,21 @@ func (m *MockObject) EXPECT() *MockObjectMockRecorder {
 	return m.recorder
 }
 
+// Attrs mocks base method.
+func (m *MockObject) Attrs(ctx context.Context) (*obj.ObjectAttrs, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Attrs", ctx)
+	ret0, _ := ret[0].(*obj.ObjectAttrs)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Attrs indicates an expected call of Attrs.
+func (mr *MockObjectMockRecorder) Attrs(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Attrs", reflect.TypeOf((*MockObject)(nil).Attrs), ctx)
+}
+
 // NewRangeReader mocks base method.
 func (m *MockObject) NewRangeReader(ctx context.Context, offset, length int64) (io.ReadCloser, error) {
 	m.ctrl.T.Helper()
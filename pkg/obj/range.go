@@ -0,0 +1,39 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package obj
+
+import "fmt"
+
+// ResolveRange converts the arguments of NewRangeReader to an absolute offset
+// and length within an object of size bytes, following the semantics of
+// Cloud Storage range reads.
+// A negative offset is relative to the end of the object, and a negative length
+// reads until the end of the object. A range that starts beyond the end of
+// the object, or a non-empty range starting at the end, returns ErrInvalidRange.
+func ResolveRange(offset, length, size int64) (int64, int64, error) {
+	if offset < 0 {
+		offset += size
+		if offset < 0 {
+			offset = 0
+		}
+	}
+	if offset > size || (offset == size && length > 0) {
+		return 0, 0, fmt.Errorf("%w: offset = %d, size = %d", ErrInvalidRange, offset, size)
+	}
+	if length < 0 || offset+length > size {
+		length = size - offset
+	}
+	return offset, length, nil
+}
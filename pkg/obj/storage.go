@@ -16,9 +16,20 @@ package obj
 
 import (
 	"context"
+	"errors"
 	"io"
 )
 
+// ErrObjectNotExist is returned when the requested object doesn't exist.
+var ErrObjectNotExist = errors.New("obj: object doesn't exist")
+
+// ErrInvalidRange is returned when the requested range starts at or beyond
+// the end of the object.
+var ErrInvalidRange = errors.New("obj: invalid range")
+
+// Done is returned by ObjectIterator.Next when there are no more entries.
+var Done = errors.New("obj: no more entries in iterator")
+
 //go:generate go run github.com/golang/mock/mockgen -source=$GOFILE -destination=./mocks/storage.go
 
 // Client is an interface for object storage.
@@ -33,11 +44,42 @@ type Client interface {
 type Bucket interface {
 	// Object returns a handle to an object specified by name.
 	Object(name string) Object
+	// Objects returns an iterator over the objects matching q in lexical order.
+	// A nil q matches all the objects.
+	Objects(ctx context.Context, q *Query) ObjectIterator
+}
+
+// Query selects objects to list.
+type Query struct {
+	// Prefix restricts the results to the objects whose names start with it.
+	Prefix string
+	// Delimiter, if not empty, collapses the objects whose names contain it after
+	// Prefix into a single entry with ListEntry.Prefix set to the name up to and
+	// including the delimiter, like directories.
+	Delimiter string
+}
+
+// ListEntry is an entry returned by ObjectIterator.
+// Exactly one of Name and Prefix is set.
+type ListEntry struct {
+	// Name is the name of the object.
+	Name string
+	// Prefix is the collapsed prefix if Query.Delimiter is set.
+	Prefix string
+}
+
+// ObjectIterator iterates over the results of Bucket.Objects.
+type ObjectIterator interface {
+	// Next returns the next entry. It returns Done if there are no more entries.
+	Next() (*ListEntry, error)
 }
 
 // Object is an interface to an object in object storage.
 type Object interface {
 	// NewRangeReader returns a new io.ReadCloser for the section [offset, offset+length)
-	// for the object.
+	// for the object. If length is negative, it reads until the end of the object.
+	// If offset is negative, it's relative to the end of the object.
 	NewRangeReader(ctx context.Context, offset, length int64) (io.ReadCloser, error)
+	// Attrs returns the attributes of the object.
+	Attrs(ctx context.Context) (*ObjectAttrs, error)
 }
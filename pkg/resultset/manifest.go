@@ -0,0 +1,137 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resultset
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/googlecloudplatform/pi-delivery/pkg/obj"
+)
+
+// Manifest is a list of ResultSets stored in a bucket.
+// It's the runtime-loadable equivalent of the generated index package.
+type Manifest struct {
+	// BucketName is the name of the bucket containing the YCD files.
+	BucketName string `json:"bucketName"`
+	// ResultSets are the result sets keyed by their names (e.g. "Decimal").
+	ResultSets map[string]ResultSet `json:"resultSets"`
+	// Constants maps the names of ResultSets to the constants they contain
+	// (e.g. "EDecimal": "e"). Result sets not listed here are Pi.
+	Constants map[string]string `json:"constants,omitempty"`
+}
+
+// LoadManifest reads a JSON manifest from r.
+// Each ResultSet in the manifest is sorted by BlockID.
+func LoadManifest(r io.Reader) (*Manifest, error) {
+	m := new(Manifest)
+	if err := json.NewDecoder(r).Decode(m); err != nil {
+		return nil, fmt.Errorf("failed to decode manifest: %w", err)
+	}
+	if len(m.ResultSets) == 0 {
+		return nil, errors.New("manifest has no result sets")
+	}
+	for name := range m.Constants {
+		if _, ok := m.ResultSets[name]; !ok {
+			return nil, fmt.Errorf("manifest has a constant for an unknown result set: %s", name)
+		}
+	}
+	for name, set := range m.ResultSets {
+		if len(set) == 0 {
+			return nil, fmt.Errorf("manifest has an empty result set: %s", name)
+		}
+		for _, f := range set {
+			if f == nil || f.Header == nil {
+				return nil, fmt.Errorf("manifest has a file without a header: %s", name)
+			}
+		}
+		sort.Sort(set)
+	}
+	return m, nil
+}
+
+// LoadManifestObject reads a JSON manifest from object.
+func LoadManifestObject(ctx context.Context, object obj.Object) (*Manifest, error) {
+	rd, err := object.NewRangeReader(ctx, 0, -1)
+	if err != nil {
+		return nil, err
+	}
+	defer rd.Close()
+	return LoadManifest(rd)
+}
+
+// LoadManifestLocation reads a manifest from location, which is either
+// a local file path or a Cloud Storage URL (gs://bucket/object) read with client.
+func LoadManifestLocation(ctx context.Context, client obj.Client, location string) (*Manifest, error) {
+	if rest, ok := strings.CutPrefix(location, "gs://"); ok {
+		bucket, object, ok := strings.Cut(rest, "/")
+		if !ok || bucket == "" || object == "" {
+			return nil, fmt.Errorf("invalid manifest URL: %s", location)
+		}
+		return LoadManifestObject(ctx, client.Bucket(bucket).Object(object))
+	}
+	f, err := os.Open(location)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return LoadManifest(f)
+}
+
+// WriteManifest writes m to w as indented JSON.
+func WriteManifest(w io.Writer, m *Manifest) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(m)
+}
+
+// Names returns the names of the result sets in m in lexical order.
+func (m *Manifest) Names() []string {
+	names := make([]string, 0, len(m.ResultSets))
+	for name := range m.ResultSets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Constant returns the name of the constant the result set name contains.
+func (m *Manifest) Constant(name string) string {
+	if c, ok := m.Constants[name]; ok {
+		return c
+	}
+	return Pi
+}
+
+// Registry returns a new Registry containing the result sets in m.
+func (m *Manifest) Registry() (*Registry, error) {
+	r := NewRegistry()
+	for _, name := range m.Names() {
+		set := m.ResultSets[name]
+		if _, ok := r.Get(m.Constant(name), set.Radix()); ok {
+			return nil, fmt.Errorf("manifest has duplicate result sets for %s radix %d", m.Constant(name), set.Radix())
+		}
+		if err := r.Add(m.Constant(name), set); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
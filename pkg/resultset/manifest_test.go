@@ -0,0 +1,202 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resultset_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googlecloudplatform/pi-delivery/pkg/obj/memory"
+	"github.com/googlecloudplatform/pi-delivery/pkg/resultset"
+	"github.com/googlecloudplatform/pi-delivery/pkg/ycd"
+)
+
+func TestManifest_RoundTrip(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	m := &resultset.Manifest{
+		BucketName: "pi100t",
+		ResultSets: map[string]resultset.ResultSet{
+			"Decimal": {
+				{
+					Header: &ycd.Header{
+						FileVersion: "1.1.0",
+						Radix:       10,
+						FirstDigits: "3.14159265358979323846264338327950288419716939937510",
+						TotalDigits: int64(0),
+						BlockSize:   int64(100),
+						BlockID:     int64(0),
+						Length:      198,
+					},
+					Name:             "Pi - Dec - Chudnovsky/Pi - Dec - Chudnovsky - 0.ycd",
+					FirstDigitOffset: 201,
+				},
+			},
+			"Hexadecimal": {
+				{
+					Header: &ycd.Header{
+						FileVersion: "1.1.0",
+						Radix:       16,
+						FirstDigits: "3.243f6a8885a308d313198a2e03707344a4093822299f31d008",
+						TotalDigits: int64(150),
+						BlockSize:   int64(100),
+						BlockID:     int64(1),
+						Length:      198,
+					},
+					Name:             "Pi - Hex - Chudnovsky/Pi - Hex - Chudnovsky - 1.ycd",
+					FirstDigitOffset: 201,
+				},
+				{
+					Header: &ycd.Header{
+						FileVersion: "1.1.0",
+						Radix:       16,
+						FirstDigits: "3.243f6a8885a308d313198a2e03707344a4093822299f31d008",
+						TotalDigits: int64(0),
+						BlockSize:   int64(100),
+						BlockID:     int64(0),
+						Length:      198,
+					},
+					Name:             "Pi - Hex - Chudnovsky/Pi - Hex - Chudnovsky - 0.ycd",
+					FirstDigitOffset: 201,
+				},
+			},
+		},
+	}
+
+	buf := new(bytes.Buffer)
+	if err := resultset.WriteManifest(buf, m); err != nil {
+		t.Fatalf("WriteManifest() failed: %v", err)
+	}
+	bucket := memory.NewBucket()
+	bucket.Put("manifest.json", buf.Bytes())
+
+	got, err := resultset.LoadManifestObject(ctx, bucket.Object("manifest.json"))
+	if err != nil {
+		t.Fatalf("LoadManifestObject() failed: %v", err)
+	}
+	// LoadManifest sorts the result sets.
+	hex := m.ResultSets["Hexadecimal"]
+	hex[0], hex[1] = hex[1], hex[0]
+	if diff := cmp.Diff(m, got); diff != "" {
+		t.Errorf("LoadManifestObject() = (-want, +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"Decimal", "Hexadecimal"}, got.Names()); diff != "" {
+		t.Errorf("Names() = (-want, +got):\n%s", diff)
+	}
+}
+
+func TestManifest_Errors(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name string
+		raw  string
+	}{
+		{"invalid json", `{`},
+		{"no result sets", `{"bucketName": "pi100t"}`},
+		{"empty result set", `{"resultSets": {"Decimal": []}}`},
+		{"no header", `{"resultSets": {"Decimal": [{"name": "a"}]}}`},
+		{"unknown constant set", `{"resultSets": {"Decimal": [{"header": {"radix": 10}}]}, "constants": {"EDecimal": "e"}}`},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			if _, err := resultset.LoadManifest(strings.NewReader(tc.raw)); err == nil {
+				t.Errorf("LoadManifest(%s) error = got nil, want non-nil", tc.raw)
+			}
+		})
+	}
+}
+
+func TestManifest_LoadLocation(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	const raw = `{"bucketName": "pi100t", "resultSets": {"Decimal": [{"header": {"radix": 10}}]}}`
+	client := memory.NewClient()
+	client.MemoryBucket("configs").Put("pi/manifest.json", []byte(raw))
+	path := filepath.Join(t.TempDir(), "manifest.json")
+	if err := os.WriteFile(path, []byte(raw), 0644); err != nil {
+		t.Fatalf("WriteFile() failed: %v", err)
+	}
+
+	for _, location := range []string{"gs://configs/pi/manifest.json", path} {
+		m, err := resultset.LoadManifestLocation(ctx, client, location)
+		if err != nil {
+			t.Errorf("LoadManifestLocation(%s) failed: %v", location, err)
+			continue
+		}
+		if m.BucketName != "pi100t" {
+			t.Errorf("LoadManifestLocation(%s).BucketName = got %s, want pi100t", location, m.BucketName)
+		}
+	}
+	for _, location := range []string{"gs://configs", "gs:///manifest.json", "gs://configs/none.json", filepath.Join(t.TempDir(), "none.json")} {
+		if _, err := resultset.LoadManifestLocation(ctx, client, location); err == nil {
+			t.Errorf("LoadManifestLocation(%s) error = got nil, want non-nil", location)
+		}
+	}
+}
+
+func TestManifest_Registry(t *testing.T) {
+	t.Parallel()
+
+	raw := `{
+		"resultSets": {
+			"Decimal": [{"header": {"radix": 10}}],
+			"EDecimal": [{"header": {"radix": 10}}],
+			"EHexadecimal": [{"header": {"radix": 16}}]
+		},
+		"constants": {"EDecimal": "e", "EHexadecimal": "e"}
+	}`
+	m, err := resultset.LoadManifest(strings.NewReader(raw))
+	if err != nil {
+		t.Fatalf("LoadManifest() failed: %v", err)
+	}
+	r, err := m.Registry()
+	if err != nil {
+		t.Fatalf("Registry() failed: %v", err)
+	}
+	for _, v := range []struct {
+		constant string
+		radix    int
+		want     string
+	}{
+		{"pi", 10, "Decimal"},
+		{"e", 10, "EDecimal"},
+		{"e", 16, "EHexadecimal"},
+	} {
+		set, ok := r.Get(v.constant, v.radix)
+		if !ok {
+			t.Errorf("Get(%s, %d) = not found", v.constant, v.radix)
+		} else if diff := cmp.Diff(m.ResultSets[v.want], set); diff != "" {
+			t.Errorf("Get(%s, %d) = (-want, +got):\n%s", v.constant, v.radix, diff)
+		}
+	}
+	if _, ok := r.Get("pi", 16); ok {
+		t.Errorf("Get(pi, 16) = found, want not found")
+	}
+
+	m.Constants["Decimal"] = "e"
+	if _, err := m.Registry(); err == nil {
+		t.Errorf("Registry() with duplicates error = got nil, want non-nil")
+	}
+}
@@ -27,6 +27,7 @@ import (
 // as necessary. Alternatively you can also use ReadAt to read a section of ResultSet.
 // Must be created by NewReader() and the caller must Close() after use.
 type Reader struct {
+	ctx    context.Context
 	set    ResultSet
 	bucket obj.Bucket
 	off    int64
@@ -38,8 +39,8 @@ type Reader struct {
 var _ io.ReadSeekCloser = new(Reader)
 var _ io.ReaderAt = new(Reader)
 
-func readOnce(set ResultSet, bucket obj.Bucket, p []byte, off int64) (int, error) {
-	reader, err := newRangeReader(context.Background(), set, bucket, off, int64(len(p)))
+func readOnce(ctx context.Context, set ResultSet, bucket obj.Bucket, p []byte, off int64) (int, error) {
+	reader, err := newRangeReader(ctx, set, bucket, off, int64(len(p)))
 	if err != nil {
 		return 0, err
 	}
@@ -51,11 +52,21 @@ func readOnce(set ResultSet, bucket obj.Bucket, p []byte, off int64) (int, error
 // ReadAt reads len(p) bytes of packed digits starting at byte result offset
 // (first byte in the result set is 0).
 // Returns io.EOF at the end of the result set.
+// It uses the context passed to NewReader.
 func (r *Reader) ReadAt(p []byte, off int64) (int, error) {
+	return r.ReadAtContext(r.ctx, p, off)
+}
+
+// ReadAtContext is the same as ReadAt but uses ctx for the reads
+// instead of the context passed to NewReader.
+func (r *Reader) ReadAtContext(ctx context.Context, p []byte, off int64) (int, error) {
 	n := 0
 
 	for n < len(p) {
-		read, err := readOnce(r.set, r.bucket, p[n:], off+int64(n))
+		if err := ctx.Err(); err != nil {
+			return n, err
+		}
+		read, err := readOnce(ctx, r.set, r.bucket, p[n:], off+int64(n))
 		n += read
 		if err == io.ErrUnexpectedEOF {
 			continue
@@ -70,12 +81,16 @@ func (r *Reader) ReadAt(p []byte, off int64) (int, error) {
 // Read reads len(p) bytes of packed digits at the current position.
 // Read returns at the end of each block with error == nil.
 // Callers should continue to call Read() if it needs more digits.
+// It uses the context passed to NewReader.
 func (r *Reader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
 	if r.rd == nil || r.seeked {
 		if err := r.Close(); err != nil {
 			return 0, err
 		}
-		reader, err := newRangeReader(context.Background(), r.set, r.bucket, r.off, -1)
+		reader, err := newRangeReader(r.ctx, r.set, r.bucket, r.off, -1)
 		r.rd = reader
 		r.seeked = false
 		if err != nil {
@@ -271,9 +271,8 @@ func TestResultSet_IOTest(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 			ctx := context.Background()
-			mockCtrl := gomock.NewController(t)
 			testBuf := tests.GenTestByteSeq(int(tc.set.TotalByteLength()))
-			bucket := tests.NewMockBucket(ctx, mockCtrl, tc.set, testBuf)
+			bucket := tests.NewTestBucket(tc.set, testBuf)
 
 			rd := tc.set.NewReader(ctx, bucket)
 			t.Cleanup(func() {
@@ -287,3 +286,84 @@ func TestResultSet_IOTest(t *testing.T) {
 		})
 	}
 }
+
+type testContextKey string
+
+func TestResultSet_Context(t *testing.T) {
+	t.Parallel()
+
+	testSet := resultset.ResultSet{
+		{
+			Header: &ycd.Header{
+				Radix:     10,
+				BlockSize: int64(100),
+				BlockID:   int64(0),
+			},
+			Name:             "Pi - Dec - Chudnovsky/Pi - Dec - Chudnovsky - 0.ycd",
+			FirstDigitOffset: 201,
+		},
+	}
+	testBuf := tests.GenTestByteSeq(int(testSet.TotalByteLength()))
+
+	t.Run("propagation", func(t *testing.T) {
+		t.Parallel()
+		mockCtrl := gomock.NewController(t)
+		newCtx := context.WithValue(context.Background(), testContextKey("reader"), "new")
+		readCtx := context.WithValue(context.Background(), testContextKey("reader"), "read")
+
+		bucket := mock_obj.NewMockBucket(mockCtrl)
+		object := mock_obj.NewMockObject(mockCtrl)
+		bucket.EXPECT().Object(testSet[0].Name).Return(object).Times(3)
+		gomock.InOrder(
+			object.EXPECT().
+				NewRangeReader(newCtx, int64(testSet[0].FirstDigitOffset), int64(8)).
+				Return(io.NopCloser(bytes.NewReader(testBuf[:8])), nil),
+			object.EXPECT().
+				NewRangeReader(readCtx, int64(testSet[0].FirstDigitOffset), int64(8)).
+				Return(io.NopCloser(bytes.NewReader(testBuf[:8])), nil),
+			object.EXPECT().
+				NewRangeReader(newCtx, int64(testSet[0].FirstDigitOffset), testSet.BlockByteLength()).
+				Return(io.NopCloser(bytes.NewReader(testBuf)), nil),
+		)
+
+		rd := testSet.NewReader(newCtx, bucket)
+		t.Cleanup(func() {
+			if err := rd.Close(); err != nil {
+				t.Errorf("Close() failed: %v", err)
+			}
+		})
+		buf := make([]byte, 8)
+		if _, err := rd.ReadAt(buf, 0); err != nil {
+			t.Errorf("ReadAt() failed: %v", err)
+		}
+		if _, err := rd.ReadAtContext(readCtx, buf, 0); err != nil {
+			t.Errorf("ReadAtContext() failed: %v", err)
+		}
+		if _, err := rd.Read(buf); err != nil {
+			t.Errorf("Read() failed: %v", err)
+		}
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		t.Parallel()
+		ctx, cancel := context.WithCancel(context.Background())
+		rd := testSet.NewReader(ctx, tests.NewTestBucket(testSet, testBuf))
+		t.Cleanup(func() {
+			if err := rd.Close(); err != nil {
+				t.Errorf("Close() failed: %v", err)
+			}
+		})
+		cancel()
+
+		buf := make([]byte, 8)
+		if _, err := rd.ReadAt(buf, 0); !errors.Is(err, context.Canceled) {
+			t.Errorf("ReadAt() error = got %v, want %v", err, context.Canceled)
+		}
+		if _, err := rd.Read(buf); !errors.Is(err, context.Canceled) {
+			t.Errorf("Read() error = got %v, want %v", err, context.Canceled)
+		}
+		if _, err := rd.ReadAtContext(context.Background(), buf, 0); err != nil {
+			t.Errorf("ReadAtContext() failed: %v", err)
+		}
+	})
+}
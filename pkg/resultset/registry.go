@@ -0,0 +1,73 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resultset
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Pi is the name of the default constant.
+const Pi = "pi"
+
+type registryKey struct {
+	constant string
+	radix    int
+}
+
+// Registry is a collection of ResultSets keyed by constant name and radix.
+// Constant names are normalized with NormalizeConstant so "Golden Ratio",
+// "golden-ratio" and "goldenratio" refer to the same constant.
+type Registry struct {
+	sets map[registryKey]ResultSet
+}
+
+// NewRegistry returns a new empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{sets: make(map[registryKey]ResultSet)}
+}
+
+// NormalizeConstant returns the canonical form of a constant name:
+// lower case letters and digits only (e.g. "Sqrt(2)" becomes "sqrt2").
+func NormalizeConstant(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(unicode.ToLower(r))
+		}
+	}
+	return b.String()
+}
+
+// Add registers set as constant. The radix is taken from set.
+// It replaces an existing set with the same constant and radix.
+func (r *Registry) Add(constant string, set ResultSet) error {
+	name := NormalizeConstant(constant)
+	if name == "" {
+		return fmt.Errorf("invalid constant name: %q", constant)
+	}
+	if len(set) == 0 {
+		return fmt.Errorf("empty result set for constant %s", name)
+	}
+	r.sets[registryKey{constant: name, radix: set.Radix()}] = set
+	return nil
+}
+
+// Get returns the ResultSet for constant and radix.
+func (r *Registry) Get(constant string, radix int) (ResultSet, bool) {
+	set, ok := r.sets[registryKey{constant: NormalizeConstant(constant), radix: radix}]
+	return set, ok
+}
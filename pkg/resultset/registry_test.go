@@ -0,0 +1,95 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resultset_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googlecloudplatform/pi-delivery/pkg/resultset"
+	"github.com/googlecloudplatform/pi-delivery/pkg/ycd"
+)
+
+func TestRegistry_NormalizeConstant(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name string
+		want string
+	}{
+		{"Pi", "pi"},
+		{"e", "e"},
+		{"Sqrt(2)", "sqrt2"},
+		{"Golden Ratio", "goldenratio"},
+		{"golden-ratio", "goldenratio"},
+		{"()", ""},
+	}
+	for _, tc := range testCases {
+		if got := resultset.NormalizeConstant(tc.name); got != tc.want {
+			t.Errorf("NormalizeConstant(%q) = got %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestRegistry_AddGet(t *testing.T) {
+	t.Parallel()
+
+	newSet := func(radix int) resultset.ResultSet {
+		return resultset.ResultSet{{Header: &ycd.Header{Radix: radix}}}
+	}
+	piDec, piHex, phiDec := newSet(10), newSet(16), newSet(10)
+
+	r := resultset.NewRegistry()
+	for _, v := range []struct {
+		constant string
+		set      resultset.ResultSet
+	}{
+		{"Pi", piDec},
+		{"pi", piHex},
+		{"Golden Ratio", phiDec},
+	} {
+		if err := r.Add(v.constant, v.set); err != nil {
+			t.Fatalf("Add(%s) failed: %v", v.constant, err)
+		}
+	}
+	if err := r.Add("()", piDec); err == nil {
+		t.Errorf("Add() with an invalid name error = got nil, want non-nil")
+	}
+	if err := r.Add("e", resultset.ResultSet{}); err == nil {
+		t.Errorf("Add() with an empty set error = got nil, want non-nil")
+	}
+
+	testCases := []struct {
+		constant string
+		radix    int
+		want     resultset.ResultSet
+		wantOK   bool
+	}{
+		{"pi", 10, piDec, true},
+		{"Pi", 16, piHex, true},
+		{"golden-ratio", 10, phiDec, true},
+		{"golden-ratio", 16, nil, false},
+		{"e", 10, nil, false},
+	}
+	for _, tc := range testCases {
+		got, ok := r.Get(tc.constant, tc.radix)
+		if ok != tc.wantOK {
+			t.Errorf("Get(%s, %d) ok = got %v, want %v", tc.constant, tc.radix, ok, tc.wantOK)
+		}
+		if diff := cmp.Diff(tc.want, got); diff != "" {
+			t.Errorf("Get(%s, %d) = (-want, +got):\n%s", tc.constant, tc.radix, diff)
+		}
+	}
+}
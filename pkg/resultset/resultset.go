@@ -16,6 +16,8 @@ package resultset
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io"
 	"sort"
 
@@ -44,13 +46,65 @@ func (s ResultSet) Swap(i, j int) {
 }
 
 // NewReader returns a new ResultSetReader with bucket.
+// ctx is used for all the reads from bucket unless a method takes
+// its own context.
 func (s ResultSet) NewReader(ctx context.Context, bucket obj.Bucket) *Reader {
 	return &Reader{
+		ctx:    ctx,
 		bucket: bucket,
 		set:    s,
 	}
 }
 
+// Validate checks the invariants the other methods depend on: every file
+// has a header with the same radix, block size and first digits, BlockIDs are
+// contiguous from 0 in order, and TotalDigits, where set, agree with each other
+// and end in the last block.
+func (s ResultSet) Validate() error {
+	if len(s) == 0 {
+		return errors.New("empty result set")
+	}
+	for i, f := range s {
+		if f == nil || f.Header == nil {
+			return fmt.Errorf("file %d has no header", i)
+		}
+	}
+	first := s[0].Header
+	if first.Radix != 10 && first.Radix != 16 {
+		return fmt.Errorf("%s: unknown radix: %d", s[0].Name, first.Radix)
+	}
+	if first.BlockSize <= 0 {
+		return fmt.Errorf("%s: invalid block size: %d", s[0].Name, first.BlockSize)
+	}
+	total := int64(0)
+	for i, f := range s {
+		h := f.Header
+		switch {
+		case h.BlockID != int64(i):
+			return fmt.Errorf("%s: BlockID = %d, want %d", f.Name, h.BlockID, i)
+		case h.Radix != first.Radix:
+			return fmt.Errorf("%s: radix = %d, want %d", f.Name, h.Radix, first.Radix)
+		case h.BlockSize != first.BlockSize:
+			return fmt.Errorf("%s: block size = %d, want %d", f.Name, h.BlockSize, first.BlockSize)
+		case h.FirstDigits != first.FirstDigits:
+			return fmt.Errorf("%s: first digits = %s, want %s", f.Name, h.FirstDigits, first.FirstDigits)
+		case h.TotalDigits != 0 && total != 0 && h.TotalDigits != total:
+			return fmt.Errorf("%s: total digits = %d, want %d", f.Name, h.TotalDigits, total)
+		}
+		if h.TotalDigits != 0 {
+			total = h.TotalDigits
+		}
+	}
+	if total != 0 {
+		n := int64(len(s))
+		if total <= (n-1)*first.BlockSize || total > n*first.BlockSize {
+			return fmt.Errorf("total digits %d isn't in the last block: %d blocks of %d digits",
+				total, n, first.BlockSize)
+		}
+	}
+	return nil
+}
+
 // TotalDigits returns the total number of digits in the array.
 // y-cruncher doesn't seem to set TotalDigits unless a particular ycd file has
 // a smaller number of digits smaller than the block size.
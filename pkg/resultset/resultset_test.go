@@ -284,3 +284,71 @@ func TestResultSet_Sets(t *testing.T) {
 		})
 	}
 }
+
+func TestResultSet_Validate(t *testing.T) {
+	t.Parallel()
+
+	newSet := func(n int, modify func(set resultset.ResultSet)) resultset.ResultSet {
+		set := resultset.ResultSet{}
+		for i := 0; i < n; i++ {
+			set = append(set, &ycd.YCDFile{
+				Header: &ycd.Header{
+					Radix:       10,
+					FirstDigits: "3.14",
+					BlockSize:   100,
+					BlockID:     int64(i),
+				},
+				Name: fmt.Sprintf("%d.ycd", i),
+			})
+		}
+		modify(set)
+		return set
+	}
+
+	testCases := []struct {
+		name    string
+		set     resultset.ResultSet
+		wantErr bool
+	}{
+		{"ok", newSet(3, func(set resultset.ResultSet) {}), false},
+		{"total in last block", newSet(3, func(set resultset.ResultSet) { set[2].Header.TotalDigits = 201 }), false},
+		{"total in every block", newSet(3, func(set resultset.ResultSet) {
+			for _, f := range set {
+				f.Header.TotalDigits = 300
+			}
+		}), false},
+		{"empty", resultset.ResultSet{}, true},
+		{"nil header", newSet(2, func(set resultset.ResultSet) { set[1].Header = nil }), true},
+		{"unknown radix", newSet(2, func(set resultset.ResultSet) {
+			for _, f := range set {
+				f.Header.Radix = 8
+			}
+		}), true},
+		{"zero block size", newSet(1, func(set resultset.ResultSet) { set[0].Header.BlockSize = 0 }), true},
+		{"gap", newSet(3, func(set resultset.ResultSet) { set[2].Header.BlockID = 3 }), true},
+		{"not sorted", newSet(2, func(set resultset.ResultSet) { set[0], set[1] = set[1], set[0] }), true},
+		{"not from 0", newSet(2, func(set resultset.ResultSet) {
+			for _, f := range set {
+				f.Header.BlockID++
+			}
+		}), true},
+		{"radix", newSet(2, func(set resultset.ResultSet) { set[1].Header.Radix = 16 }), true},
+		{"block size", newSet(2, func(set resultset.ResultSet) { set[1].Header.BlockSize = 99 }), true},
+		{"first digits", newSet(2, func(set resultset.ResultSet) { set[1].Header.FirstDigits = "3.15" }), true},
+		{"total disagrees", newSet(3, func(set resultset.ResultSet) {
+			set[1].Header.TotalDigits = 250
+			set[2].Header.TotalDigits = 251
+		}), true},
+		{"total too small", newSet(3, func(set resultset.ResultSet) { set[2].Header.TotalDigits = 200 }), true},
+		{"total too large", newSet(3, func(set resultset.ResultSet) { set[2].Header.TotalDigits = 301 }), true},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			if err := tc.set.Validate(); (err != nil) != tc.wantErr {
+				t.Errorf("Validate() = got error %v, want error %v", err, tc.wantErr)
+			}
+		})
+	}
+}
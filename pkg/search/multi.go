@@ -0,0 +1,249 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// alphabetSize is the number of distinct digits up to hexadecimal.
+const alphabetSize = 16
+
+// digitIndex maps lowercase hexadecimal digits to 0-15 and other bytes to -1.
+var digitIndex = func() (t [256]int8) {
+	for i := range t {
+		t[i] = -1
+	}
+	for c := '0'; c <= '9'; c++ {
+		t[c] = int8(c - '0')
+	}
+	for c := 'a'; c <= 'f'; c++ {
+		t[c] = int8(c - 'a' + 10)
+	}
+	return
+}()
+
+type acNode struct {
+	// next is the goto function with failure transitions resolved,
+	// so every node has a transition for every digit.
+	next [alphabetSize]int32
+	fail int32
+	// out is the nearest node on the failure chain, including this node,
+	// that ends a pattern, or -1 if there's none.
+	out int32
+	// patterns are the indices of the patterns ending at this node.
+	patterns []int
+}
+
+// Matcher finds occurrences of multiple patterns in a single pass
+// with the Aho-Corasick algorithm. A Matcher is immutable and can be
+// shared by multiple MultiScanners.
+type Matcher struct {
+	nodes    []acNode
+	patterns [][]byte
+}
+
+// NewMatcher builds a Matcher for patterns.
+// Patterns must be non-empty and consist of digits up to hexadecimal
+// in lowercase. Duplicate patterns are reported separately.
+func NewMatcher(patterns [][]byte) (*Matcher, error) {
+	if len(patterns) == 0 {
+		return nil, errors.New("search: no patterns")
+	}
+	m := &Matcher{
+		nodes:    []acNode{{out: -1}},
+		patterns: make([][]byte, len(patterns)),
+	}
+	for i, p := range patterns {
+		if err := ValidatePattern(p, 16); err != nil {
+			return nil, fmt.Errorf("pattern %d: %w", i, err)
+		}
+		m.patterns[i] = append([]byte(nil), p...)
+		m.insert(i, p)
+	}
+	m.link()
+	return m, nil
+}
+
+// insert adds pattern i to the trie. 0 means no transition until link() runs
+// as the root can't be a child.
+func (m *Matcher) insert(i int, p []byte) {
+	n := int32(0)
+	for _, c := range p {
+		d := digitIndex[c]
+		if m.nodes[n].next[d] == 0 {
+			m.nodes = append(m.nodes, acNode{out: -1})
+			m.nodes[n].next[d] = int32(len(m.nodes) - 1)
+		}
+		n = m.nodes[n].next[d]
+	}
+	m.nodes[n].patterns = append(m.nodes[n].patterns, i)
+}
+
+// link computes failure links and output links in breadth-first order
+// and fills in missing transitions.
+func (m *Matcher) link() {
+	queue := make([]int32, 0, len(m.nodes))
+	for _, child := range m.nodes[0].next {
+		if child != 0 {
+			queue = append(queue, child)
+		}
+	}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		node := &m.nodes[n]
+		if len(node.patterns) > 0 {
+			node.out = n
+		} else {
+			node.out = m.nodes[node.fail].out
+		}
+		for d, child := range node.next {
+			if child == 0 {
+				node.next[d] = m.nodes[node.fail].next[d]
+				continue
+			}
+			m.nodes[child].fail = m.nodes[node.fail].next[d]
+			queue = append(queue, child)
+		}
+	}
+}
+
+// Len returns the number of patterns.
+func (m *Matcher) Len() int {
+	return len(m.patterns)
+}
+
+// Pattern returns the i-th pattern.
+func (m *Matcher) Pattern(i int) []byte {
+	return m.patterns[i]
+}
+
+// MultiMatch is an occurrence of a pattern.
+type MultiMatch struct {
+	// Pattern is the index of the pattern passed to NewMatcher.
+	Pattern int
+	// Offset is the offset of the first digit of the occurrence.
+	Offset int64
+}
+
+// MultiScanner finds occurrences of the patterns of a Matcher in digits
+// fed sequentially in chunks. Matches straddling chunk boundaries are found
+// as the automaton state is kept between chunks.
+type MultiScanner struct {
+	m     *Matcher
+	state int32
+	off   int64
+
+	stopAfterFirst bool
+	stopped        []bool
+	remaining      int
+}
+
+// NewScanner returns a new MultiScanner. off is the offset of the first digit
+// that will be scanned. If stopAfterFirst is true, each pattern is reported
+// only at its first occurrence.
+func (m *Matcher) NewScanner(off int64, stopAfterFirst bool) *MultiScanner {
+	s := &MultiScanner{
+		m:              m,
+		off:            off,
+		stopAfterFirst: stopAfterFirst,
+		remaining:      len(m.patterns),
+	}
+	if stopAfterFirst {
+		s.stopped = make([]bool, len(m.patterns))
+	}
+	return s
+}
+
+// Offset returns the offset of the next digit to be scanned.
+func (s *MultiScanner) Offset() int64 {
+	return s.off
+}
+
+// Done returns true if every pattern has been found with stopAfterFirst.
+func (s *MultiScanner) Done() bool {
+	return s.stopAfterFirst && s.remaining == 0
+}
+
+// Scan scans p, which must immediately follow the previously scanned digits,
+// and calls fn for each match in the order the matches end.
+// Matches ending at the same digit are reported longest first.
+// Bytes other than digits never match.
+// If fn returns false or Done() becomes true, Scan stops and returns false.
+// The MultiScanner must not be used after fn returns false.
+func (s *MultiScanner) Scan(p []byte, fn func(MultiMatch) bool) bool {
+	if s.Done() {
+		return false
+	}
+	nodes := s.m.nodes
+	state := s.state
+	for i, c := range p {
+		d := digitIndex[c]
+		if d < 0 {
+			state = 0
+			continue
+		}
+		state = nodes[state].next[d]
+		for o := nodes[state].out; o >= 0; o = nodes[nodes[o].fail].out {
+			for _, id := range nodes[o].patterns {
+				if s.stopAfterFirst {
+					if s.stopped[id] {
+						continue
+					}
+					s.stopped[id] = true
+					s.remaining--
+				}
+				end := s.off + int64(i) + 1
+				if !fn(MultiMatch{Pattern: id, Offset: end - int64(len(s.m.patterns[id]))}) {
+					return false
+				}
+			}
+		}
+		if s.Done() {
+			s.off += int64(i) + 1
+			return false
+		}
+	}
+	s.state = state
+	s.off += int64(len(p))
+	return true
+}
+
+// FindMulti reads digits from r until EOF and calls fn for each occurrence of
+// the patterns of m. off is the offset of the first digit in r.
+// It stops when fn returns false or, with stopAfterFirst, when all patterns are found.
+func FindMulti(ctx context.Context, r io.Reader, off int64, m *Matcher, stopAfterFirst bool, fn func(MultiMatch) bool) error {
+	s := m.NewScanner(off, stopAfterFirst)
+	buf := make([]byte, DefaultChunkSize)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		n, err := io.ReadFull(r, buf)
+		if n > 0 && !s.Scan(buf[:n], fn) {
+			return nil
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
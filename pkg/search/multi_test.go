@@ -0,0 +1,186 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package search
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"testing"
+	"testing/iotest"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var testPatterns = []string{"1", "14", "141", "4159", "59", "9", "999", "2", "26", "62", "8899", "89986", "1", "00000", "abc"}
+
+// naiveFindMulti returns the matches of patterns in s sorted by their end offsets,
+// then by descending length as MultiScanner reports them.
+func naiveFindMulti(s string, patterns []string, off int64) []MultiMatch {
+	type match struct {
+		MultiMatch
+		end int
+	}
+	matches := make([]match, 0)
+	for i, p := range patterns {
+		for _, o := range naiveFind(s, p, off) {
+			matches = append(matches, match{MultiMatch{i, o}, int(o) + len(p)})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].end != matches[j].end {
+			return matches[i].end < matches[j].end
+		}
+		return matches[i].Offset < matches[j].Offset
+	})
+	got := make([]MultiMatch, len(matches))
+	for i := range matches {
+		got[i] = matches[i].MultiMatch
+	}
+	return got
+}
+
+func newTestMatcher(t *testing.T, patterns []string) *Matcher {
+	t.Helper()
+	b := make([][]byte, len(patterns))
+	for i, p := range patterns {
+		b[i] = []byte(p)
+	}
+	m, err := NewMatcher(b)
+	if err != nil {
+		t.Fatalf("NewMatcher() failed: %v", err)
+	}
+	return m
+}
+
+func TestMultiScanner_ChunkBoundaries(t *testing.T) {
+	t.Parallel()
+
+	m := newTestMatcher(t, testPatterns)
+	want := naiveFindMulti(testDigits, testPatterns, 100)
+	for chunk := 1; chunk <= 12; chunk++ {
+		chunk := chunk
+		t.Run(fmt.Sprintf("chunk %d", chunk), func(t *testing.T) {
+			t.Parallel()
+			s := m.NewScanner(100, false)
+			got := make([]MultiMatch, 0)
+			for i := 0; i < len(testDigits); i += chunk {
+				end := i + chunk
+				if end > len(testDigits) {
+					end = len(testDigits)
+				}
+				s.Scan([]byte(testDigits[i:end]), func(mm MultiMatch) bool {
+					got = append(got, mm)
+					return true
+				})
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("Scan() = (-want, +got):\n%s", diff)
+			}
+			if got, want := s.Offset(), int64(100+len(testDigits)); got != want {
+				t.Errorf("Offset() = got %d, want %d", got, want)
+			}
+		})
+	}
+}
+
+func TestMultiScanner_StopAfterFirst(t *testing.T) {
+	t.Parallel()
+
+	patterns := []string{"9", "26", "1", "1", "999"}
+	m := newTestMatcher(t, patterns)
+	got := make([]MultiMatch, 0)
+	err := FindMulti(context.Background(), iotest.OneByteReader(bytes.NewReader([]byte(testDigits))), 0, m, true, func(mm MultiMatch) bool {
+		got = append(got, mm)
+		return true
+	})
+	if err != nil {
+		t.Errorf("FindMulti() failed: %v", err)
+	}
+	want := []MultiMatch{{2, 0}, {3, 0}, {0, 4}, {1, 5}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("FindMulti() = (-want, +got):\n%s", diff)
+	}
+
+	// Scanning stops once all patterns are found.
+	m = newTestMatcher(t, patterns[:3])
+	s := m.NewScanner(0, true)
+	n := 0
+	if s.Scan([]byte(testDigits), func(MultiMatch) bool { n++; return true }) {
+		t.Errorf("Scan() = got true, want false")
+	}
+	if !s.Done() || n != 3 {
+		t.Errorf("Done() = %v after %d matches, want true after 3", s.Done(), n)
+	}
+	if got, want := s.Offset(), int64(7); got != want {
+		t.Errorf("Offset() = got %d, want %d", got, want)
+	}
+}
+
+func TestFindMulti(t *testing.T) {
+	t.Parallel()
+
+	m := newTestMatcher(t, testPatterns)
+	got := make([]MultiMatch, 0)
+	err := FindMulti(context.Background(), bytes.NewReader([]byte(testDigits)), 0, m, false, func(mm MultiMatch) bool {
+		got = append(got, mm)
+		return len(got) < 5
+	})
+	if err != nil {
+		t.Errorf("FindMulti() failed: %v", err)
+	}
+	if diff := cmp.Diff(naiveFindMulti(testDigits, testPatterns, 0)[:5], got); diff != "" {
+		t.Errorf("FindMulti() = (-want, +got):\n%s", diff)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := FindMulti(ctx, bytes.NewReader([]byte(testDigits)), 0, m, false, func(MultiMatch) bool { return true }); !errors.Is(err, context.Canceled) {
+		t.Errorf("FindMulti() error = got %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestNewMatcher_Errors(t *testing.T) {
+	t.Parallel()
+
+	for _, patterns := range [][]string{{}, {""}, {"12", "1A"}, {"x"}} {
+		b := make([][]byte, len(patterns))
+		for i, p := range patterns {
+			b[i] = []byte(p)
+		}
+		if _, err := NewMatcher(b); err == nil {
+			t.Errorf("NewMatcher(%q) error = got nil, want non-nil", patterns)
+		}
+	}
+}
+
+func BenchmarkMultiScanner(b *testing.B) {
+	patterns := make([][]byte, 1000)
+	for i := range patterns {
+		patterns[i] = []byte(fmt.Sprintf("%08d", i*7919))
+	}
+	m, err := NewMatcher(patterns)
+	if err != nil {
+		b.Fatalf("NewMatcher() failed: %v", err)
+	}
+	buf := bytes.Repeat([]byte(testDigits), DefaultChunkSize/len(testDigits))
+	b.SetBytes(int64(len(buf)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.NewScanner(0, false).Scan(buf, func(MultiMatch) bool { return true })
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package search
+
+// CommonPrefixLength returns the length of the longest common prefix of a and b.
+func CommonPrefixLength(a, b []byte) int {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	return len(a)
+}
+
+// PrefixMatches finds the positions in buf[:n] where at least minMatch
+// leading digits of pattern occur and calls fn with each position and
+// the number of digits of pattern that match there, in ascending order.
+//
+// buf may extend past n by up to len(pattern)-1 digits so that matches
+// starting in buf[:n] can be measured completely. Splitting a range into
+// chunks of n digits that overlap by len(pattern)-1 digits therefore finds
+// every match exactly once, including those straddling chunk boundaries.
+// If minMatch is not positive or longer than pattern, the whole pattern must match.
+func PrefixMatches(buf []byte, n int, pattern []byte, minMatch int, fn func(pos, length int)) {
+	if len(pattern) == 0 {
+		return
+	}
+	if minMatch <= 0 || minMatch > len(pattern) {
+		minMatch = len(pattern)
+	}
+	if n > len(buf) {
+		n = len(buf)
+	}
+	prefix := pattern[:minMatch]
+	s := NewScanner(prefix, 0)
+	end := n + minMatch - 1
+	if end > len(buf) {
+		end = len(buf)
+	}
+	s.Scan(buf[:end], func(off int64) bool {
+		i := int(off)
+		fn(i, minMatch+CommonPrefixLength(buf[i+minMatch:], pattern[minMatch:]))
+		return true
+	})
+}
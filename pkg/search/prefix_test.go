@@ -0,0 +1,88 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package search
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type prefixMatch struct {
+	Pos, Length int
+}
+
+func TestCommonPrefixLength(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"123", "", 0},
+		{"123", "124", 2},
+		{"123", "12345", 3},
+		{"12345", "123", 3},
+		{"9", "1", 0},
+	}
+	for _, tc := range testCases {
+		if got := CommonPrefixLength([]byte(tc.a), []byte(tc.b)); got != tc.want {
+			t.Errorf("CommonPrefixLength(%q, %q) = got %d, want %d", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
+
+func TestPrefixMatches(t *testing.T) {
+	t.Parallel()
+
+	const digits = "31415926535897932384626433832795028841971693993751314159265358979314150314"
+	pattern := []byte("3141592653589793238462643383279502884197169399375105820974944592307816406286")
+	testCases := []struct {
+		minMatch int
+		want     []prefixMatch
+	}{
+		{4, []prefixMatch{{0, 50}, {50, 16}, {65, 5}}},
+		{6, []prefixMatch{{0, 50}, {50, 16}}},
+		{0, []prefixMatch{}},
+		{1000, []prefixMatch{}},
+		{3, []prefixMatch{{0, 50}, {50, 16}, {65, 5}, {71, 3}}},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(fmt.Sprintf("min %d", tc.minMatch), func(t *testing.T) {
+			t.Parallel()
+
+			// Scan the whole buffer at once and then in overlapping chunks
+			// as cmd/pinpi does. Both must find the same matches.
+			for chunk := 1; chunk <= len(digits); chunk++ {
+				got := make([]prefixMatch, 0)
+				for start := 0; start < len(digits); start += chunk {
+					end := start + chunk + len(pattern) - 1
+					if end > len(digits) {
+						end = len(digits)
+					}
+					PrefixMatches([]byte(digits[start:end]), chunk, pattern, tc.minMatch, func(pos, length int) {
+						got = append(got, prefixMatch{start + pos, length})
+					})
+				}
+				if diff := cmp.Diff(tc.want, got); diff != "" {
+					t.Fatalf("PrefixMatches(chunk %d) = (-want, +got):\n%s", chunk, diff)
+				}
+			}
+		})
+	}
+}
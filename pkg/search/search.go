@@ -0,0 +1,166 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package search finds digit sequences in unpacked digits.
+package search
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// DefaultChunkSize is the number of digits Find reads at a time.
+const DefaultChunkSize = 1024 * 1024
+
+// ErrEmptyPattern is returned when the pattern is empty.
+var ErrEmptyPattern = errors.New("search: empty pattern")
+
+// ValidatePattern checks that pattern only consists of digits of radix.
+// Hexadecimal digits must be lowercase as they are unpacked.
+func ValidatePattern(pattern []byte, radix int) error {
+	if len(pattern) == 0 {
+		return ErrEmptyPattern
+	}
+	for i, c := range pattern {
+		if !isDigit(c, radix) {
+			return fmt.Errorf("search: invalid digit %q at %d for radix %d", c, i, radix)
+		}
+	}
+	return nil
+}
+
+func isDigit(c byte, radix int) bool {
+	switch radix {
+	case 10:
+		return '0' <= c && c <= '9'
+	case 16:
+		return '0' <= c && c <= '9' || 'a' <= c && c <= 'f'
+	}
+	return false
+}
+
+// Scanner finds all occurrences of a pattern in digits that are fed
+// sequentially in chunks. It keeps the last len(pattern)-1 digits of the
+// previous chunk so matches straddling chunk boundaries are found exactly once.
+// Overlapping matches are all reported.
+type Scanner struct {
+	pattern []byte
+	carry   []byte
+	// off is the offset of carry[0], or of the next chunk if carry is empty.
+	off int64
+}
+
+// NewScanner returns a new Scanner for pattern.
+// off is the offset of the first digit that will be scanned.
+func NewScanner(pattern []byte, off int64) *Scanner {
+	return &Scanner{
+		pattern: bytes.Clone(pattern),
+		carry:   make([]byte, 0, len(pattern)),
+		off:     off,
+	}
+}
+
+// Offset returns the offset of the next digit to be scanned.
+func (s *Scanner) Offset() int64 {
+	return s.off + int64(len(s.carry))
+}
+
+// Scan scans p, which must immediately follow the previously scanned digits,
+// and calls fn with the offset of each match in order.
+// If fn returns false, Scan stops and returns false. The Scanner must not be
+// used after that.
+func (s *Scanner) Scan(p []byte, fn func(off int64) bool) bool {
+	m := len(s.pattern)
+	if m == 0 {
+		return true
+	}
+
+	// Matches that start in the carried digits and end in p.
+	if len(s.carry) > 0 {
+		head := p
+		if len(head) > m-1 {
+			head = head[:m-1]
+		}
+		b := append(append(make([]byte, 0, len(s.carry)+len(head)), s.carry...), head...)
+		for i := range s.carry {
+			if bytes.HasPrefix(b[i:], s.pattern) && !fn(s.off+int64(i)) {
+				return false
+			}
+		}
+	}
+
+	pOff := s.Offset()
+	for i := 0; i+m <= len(p); {
+		j := bytes.Index(p[i:], s.pattern)
+		if j < 0 {
+			break
+		}
+		if !fn(pOff + int64(i+j)) {
+			return false
+		}
+		i += j + 1
+	}
+
+	// Keep the last m-1 digits for the next chunk.
+	keep := m - 1
+	if len(p) >= keep {
+		s.carry = append(s.carry[:0], p[len(p)-keep:]...)
+		s.off = pOff + int64(len(p)-keep)
+		return true
+	}
+	s.carry = append(s.carry, p...)
+	if drop := len(s.carry) - keep; drop > 0 {
+		copy(s.carry, s.carry[drop:])
+		s.carry = s.carry[:keep]
+		s.off += int64(drop)
+	}
+	return true
+}
+
+// Find reads digits from r until EOF and returns the offsets of the first
+// limit occurrences of pattern. off is the offset of the first digit in r.
+// If limit is zero or negative, all occurrences are returned.
+// Wrap r with io.LimitReader to bound the search.
+func Find(ctx context.Context, r io.Reader, off int64, pattern []byte, limit int) ([]int64, error) {
+	if len(pattern) == 0 {
+		return nil, ErrEmptyPattern
+	}
+
+	matches := make([]int64, 0)
+	s := NewScanner(pattern, off)
+	collect := func(off int64) bool {
+		matches = append(matches, off)
+		return limit <= 0 || len(matches) < limit
+	}
+
+	buf := make([]byte, DefaultChunkSize)
+	for {
+		if err := ctx.Err(); err != nil {
+			return matches, err
+		}
+		n, err := io.ReadFull(r, buf)
+		if n > 0 && !s.Scan(buf[:n], collect) {
+			return matches, nil
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return matches, nil
+		}
+		if err != nil {
+			return matches, err
+		}
+	}
+}
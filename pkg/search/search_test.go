@@ -0,0 +1,145 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package search
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"testing/iotest"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const testDigits = "14159265358979323846264338327950288419716939937510582097494459230781640628620899862803482534211706798214808651"
+
+// naiveFind returns the offsets of all (overlapping) occurrences of pattern in s.
+func naiveFind(s, pattern string, off int64) []int64 {
+	matches := make([]int64, 0)
+	for i := 0; i+len(pattern) <= len(s); i++ {
+		if s[i:i+len(pattern)] == pattern {
+			matches = append(matches, off+int64(i))
+		}
+	}
+	return matches
+}
+
+func TestScanner_ChunkBoundaries(t *testing.T) {
+	t.Parallel()
+
+	patterns := []string{"1", "14", "59", "999", "62862", "3", "2", "88", "89986", "00000"}
+	for _, pattern := range patterns {
+		for chunk := 1; chunk <= 12; chunk++ {
+			pattern, chunk := pattern, chunk
+			t.Run(fmt.Sprintf("%s chunk %d", pattern, chunk), func(t *testing.T) {
+				t.Parallel()
+				s := NewScanner([]byte(pattern), 100)
+				got := make([]int64, 0)
+				for i := 0; i < len(testDigits); i += chunk {
+					end := i + chunk
+					if end > len(testDigits) {
+						end = len(testDigits)
+					}
+					s.Scan([]byte(testDigits[i:end]), func(off int64) bool {
+						got = append(got, off)
+						return true
+					})
+				}
+				if diff := cmp.Diff(naiveFind(testDigits, pattern, 100), got); diff != "" {
+					t.Errorf("Scan() = (-want, +got):\n%s", diff)
+				}
+				if got, want := s.Offset(), int64(100+len(testDigits)); got != want {
+					t.Errorf("Offset() = got %d, want %d", got, want)
+				}
+			})
+		}
+	}
+}
+
+func TestFind(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		pattern string
+		limit   int
+		want    []int64
+	}{
+		{"1", 0, naiveFind(testDigits, "1", 0)},
+		{"1", 3, []int64{0, 2, 36}},
+		{"26", 2, []int64{5, 20}},
+		{"5358979", 0, []int64{7}},
+		{"123456789", 0, []int64{}},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(fmt.Sprintf("%s limit %d", tc.pattern, tc.limit), func(t *testing.T) {
+			t.Parallel()
+			// OneByteReader makes sure short reads are handled.
+			r := iotest.OneByteReader(bytes.NewReader([]byte(testDigits)))
+			got, err := Find(context.Background(), r, 0, []byte(tc.pattern), tc.limit)
+			if err != nil {
+				t.Errorf("Find() failed: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Find() = (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFind_Errors(t *testing.T) {
+	t.Parallel()
+
+	if _, err := Find(context.Background(), bytes.NewReader(nil), 0, nil, 0); !errors.Is(err, ErrEmptyPattern) {
+		t.Errorf("Find() error = got %v, want %v", err, ErrEmptyPattern)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Find(ctx, bytes.NewReader([]byte(testDigits)), 0, []byte("1"), 0); !errors.Is(err, context.Canceled) {
+		t.Errorf("Find() error = got %v, want %v", err, context.Canceled)
+	}
+
+	errRead := errors.New("read error")
+	if _, err := Find(context.Background(), iotest.ErrReader(errRead), 0, []byte("1"), 0); !errors.Is(err, errRead) {
+		t.Errorf("Find() error = got %v, want %v", err, errRead)
+	}
+}
+
+func TestValidatePattern(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		pattern string
+		radix   int
+		valid   bool
+	}{
+		{"0123456789", 10, true},
+		{"0123456789abcdef", 16, true},
+		{"", 10, false},
+		{"12a", 10, false},
+		{"12A", 16, false},
+		{"12g", 16, false},
+		{"12", 8, false},
+	}
+	for _, tc := range testCases {
+		err := ValidatePattern([]byte(tc.pattern), tc.radix)
+		if got := err == nil; got != tc.valid {
+			t.Errorf("ValidatePattern(%q, %d) = got %v, want valid %v", tc.pattern, tc.radix, err, tc.valid)
+		}
+	}
+}
@@ -15,35 +15,195 @@
 package service
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"io"
+	"strconv"
+	"sync"
 
+	"github.com/googlecloudplatform/pi-delivery/gen/index"
 	"github.com/googlecloudplatform/pi-delivery/pkg/cached"
 	"github.com/googlecloudplatform/pi-delivery/pkg/obj"
 	"github.com/googlecloudplatform/pi-delivery/pkg/obj/gcs"
 	"github.com/googlecloudplatform/pi-delivery/pkg/resultset"
+	"github.com/googlecloudplatform/pi-delivery/pkg/search"
+	"github.com/googlecloudplatform/pi-delivery/pkg/stats"
 	"github.com/googlecloudplatform/pi-delivery/pkg/unpack"
 	"go.uber.org/zap"
 )
 
 var errInternal = errors.New("internal error")
 
+// streamChunkSize is the number of digits Stream unpacks at a time.
+const streamChunkSize = 64 * 1024
+
+// CachePolicy specifies how the service caches digits.
+type CachePolicy int
+
+const (
+	// CacheFirstBytes caches the first bytes of each result set in memory.
+	// Each service has its own cache.
+	CacheFirstBytes CachePolicy = iota
+	// CacheNone disables caching.
+	CacheNone
+)
+
 type Service struct {
-	storage obj.Client
-	bucket  obj.Bucket
+	storage  obj.Client
+	bucket   obj.Bucket
+	registry *resultset.Registry
+	// blocks is nil unless the cache policy is CacheFirstBytes.
+	blocks *cached.Cache
+
+	// histograms caches *stats.HistogramIndex by the first file of the result set.
+	histograms sync.Map
+	// statistics caches *stats.Stats by the first file of the result set.
+	statistics sync.Map
+}
+
+// Option is an option for New.
+type Option func(*options)
+
+type constantSet struct {
+	constant string
+	set      resultset.ResultSet
+}
+
+type options struct {
+	bucketName string
+	manifest   *resultset.Manifest
+	sets       []constantSet
+	cache      CachePolicy
+}
+
+// WithBucketName sets the bucket name to read objects from.
+// The default is index.BucketName.
+func WithBucketName(name string) Option {
+	return func(o *options) {
+		o.bucketName = name
+	}
+}
+
+// WithResultSets sets the result sets of pi the service serves,
+// replacing all the other result sets.
+// Each result set is keyed by its radix and a later set overrides an earlier one
+// with the same radix. The default is index.Decimal and index.Hexadecimal.
+func WithResultSets(sets ...resultset.ResultSet) Option {
+	return func(o *options) {
+		o.manifest = nil
+		o.sets = nil
+		for _, set := range sets {
+			o.sets = append(o.sets, constantSet{constant: resultset.Pi, set: set})
+		}
+	}
+}
+
+// WithConstant adds the result sets of constant (e.g. "e") to the service.
+// Each result set is keyed by constant and its radix.
+func WithConstant(constant string, sets ...resultset.ResultSet) Option {
+	return func(o *options) {
+		for _, set := range sets {
+			o.sets = append(o.sets, constantSet{constant: constant, set: set})
+		}
+	}
+}
+
+// WithManifest sets the bucket name and the result sets from m,
+// replacing all the other result sets.
+// The bucket name is left unchanged if m doesn't have one.
+// New fails if m has more than one result set for the same constant and radix.
+func WithManifest(m *resultset.Manifest) Option {
+	return func(o *options) {
+		if m.BucketName != "" {
+			o.bucketName = m.BucketName
+		}
+		o.manifest = m
+		o.sets = nil
+	}
+}
+
+// WithCachePolicy sets the cache policy. The default is CacheFirstBytes.
+func WithCachePolicy(p CachePolicy) Option {
+	return func(o *options) {
+		o.cache = p
+	}
+}
+
+// New returns a new Service that reads digits using client.
+// The service takes the ownership of client and closes it in Close().
+func New(client obj.Client, opts ...Option) (*Service, error) {
+	if client == nil {
+		return nil, errors.New("service: nil storage client")
+	}
+	o := &options{
+		bucketName: index.BucketName,
+		cache:      CacheFirstBytes,
+	}
+	WithResultSets(index.Decimal, index.Hexadecimal)(o)
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.bucketName == "" {
+		return nil, errors.New("service: empty bucket name")
+	}
+	registry := resultset.NewRegistry()
+	if m := o.manifest; m != nil {
+		for _, name := range m.Names() {
+			if err := m.ResultSets[name].Validate(); err != nil {
+				return nil, fmt.Errorf("service: invalid result set %s: %w", name, err)
+			}
+		}
+		var err error
+		if registry, err = m.Registry(); err != nil {
+			return nil, fmt.Errorf("service: %w", err)
+		}
+	}
+	for _, v := range o.sets {
+		if err := v.set.Validate(); err != nil {
+			return nil, fmt.Errorf("service: invalid result set of %s: %w", v.constant, err)
+		}
+		if err := registry.Add(v.constant, v.set); err != nil {
+			return nil, fmt.Errorf("service: %w", err)
+		}
+	}
+	s := &Service{
+		storage:  client,
+		bucket:   client.Bucket(o.bucketName),
+		registry: registry,
+	}
+	if o.cache == CacheFirstBytes {
+		s.blocks = cached.NewCache()
+	}
+	return s, nil
 }
 
+// NewService returns a new Service reading from bucketName on Cloud Storage.
+// It terminates the program if it fails to create a Storage client.
+//
+// Deprecated: Use New instead.
 func NewService(ctx context.Context, logger *zap.SugaredLogger, bucketName string) *Service {
 	storageClient, err := gcs.NewClient(ctx)
 	if err != nil {
 		logger.Fatalw("Failed to create a new Storage client",
 			"error", err)
 	}
-	return &Service{
-		storage: storageClient,
-		bucket:  storageClient.Bucket(bucketName),
+	s, err := New(storageClient, WithBucketName(bucketName))
+	if err != nil {
+		logger.Fatalw("Failed to create a new Service",
+			"error", err)
+	}
+	return s
+}
+
+// ResultSet returns the result set for constant and radix.
+func (s *Service) ResultSet(constant string, radix int) (resultset.ResultSet, error) {
+	set, ok := s.registry.Get(constant, radix)
+	if !ok {
+		return nil, fmt.Errorf("no result set for constant %s radix %d", constant, radix)
 	}
+	return set, nil
 }
 
 // Get returns n bytes of pi starting at start.
@@ -72,8 +232,8 @@ func (s *Service) Get(ctx context.Context, logger *zap.SugaredLogger, set result
 
 	rr := set.NewReader(ctx, s.bucket)
 	defer rr.Close()
-	reader := unpack.NewReader(ctx, cached.NewCachedReader(ctx, rr))
-	read, err := reader.ReadAt(unpacked[off:], start)
+	reader := s.newUnpackReader(ctx, rr)
+	read, err := reader.ReadAtContext(ctx, unpacked[off:], start)
 
 	if err != nil && !errors.Is(err, io.EOF) {
 		logger.Errorw("ReadAt returned error",
@@ -88,6 +248,192 @@ func (s *Service) Get(ctx context.Context, logger *zap.SugaredLogger, set result
 	return unpacked[:read], nil
 }
 
+// Stream writes n digits starting at start to w as they are unpacked,
+// without buffering the whole range in memory.
+// The first digit (position 0) is 3 before the decimal point as in Get.
+// It stops at the end of the result set and returns the number of digits written.
+// Errors from w are returned as is.
+func (s *Service) Stream(ctx context.Context, logger *zap.SugaredLogger, set resultset.ResultSet, w io.Writer, start, n int64) (int64, error) {
+	logger = logger.With("start", start, "n", n)
+
+	written := int64(0)
+	if n == 0 {
+		return 0, nil
+	}
+	if start == 0 {
+		if _, err := w.Write([]byte{set.FirstDigit()}); err != nil {
+			return 0, err
+		}
+		written++
+		n--
+	} else {
+		start--
+	}
+	if n == 0 || start >= set.TotalDigits() {
+		return written, nil
+	}
+
+	rr := set.NewReader(ctx, s.bucket)
+	defer rr.Close()
+	reader := s.newUnpackReader(ctx, rr)
+	if _, err := reader.Seek(start, io.SeekStart); err != nil {
+		logger.Errorw("Seek returned error",
+			"error", err,
+		)
+		return written, errInternal
+	}
+
+	buf := make([]byte, streamChunkSize)
+	for n > 0 {
+		if err := ctx.Err(); err != nil {
+			return written, err
+		}
+		chunk := buf
+		if int64(len(chunk)) > n {
+			chunk = chunk[:n]
+		}
+		read, err := reader.Read(chunk)
+		if read > 0 {
+			if _, err := w.Write(chunk[:read]); err != nil {
+				return written, err
+			}
+			written += int64(read)
+			n -= int64(read)
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			logger.Errorw("Read returned error",
+				"error", err,
+				"written", written,
+			)
+			return written, errInternal
+		}
+	}
+	return written, nil
+}
+
+// Search returns the positions of up to limit occurrences of pattern in the
+// n digits starting at start. Only matches that fit entirely in the range are returned.
+// Positions count the first digit (3) as position 0 as in Get.
+// If limit is zero or negative, all occurrences are returned.
+func (s *Service) Search(ctx context.Context, logger *zap.SugaredLogger, set resultset.ResultSet, pattern []byte, start, n int64, limit int) ([]int64, error) {
+	logger = logger.With("start", start, "n", n, "limit", limit)
+
+	if err := search.ValidatePattern(pattern, set.Radix()); err != nil {
+		return nil, err
+	}
+	if n == 0 || start > set.TotalDigits() {
+		return []int64{}, nil
+	}
+
+	rr := set.NewReader(ctx, s.bucket)
+	defer rr.Close()
+	reader := s.newUnpackReader(ctx, rr)
+
+	var digits io.Reader = reader
+	if start == 0 {
+		digits = io.MultiReader(bytes.NewReader([]byte{set.FirstDigit()}), reader)
+	} else if _, err := reader.Seek(start-1, io.SeekStart); err != nil {
+		logger.Errorw("Seek returned error",
+			"error", err,
+		)
+		return nil, errInternal
+	}
+
+	matches, err := search.Find(ctx, io.LimitReader(digits, n), start, pattern, limit)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return matches, ctxErr
+		}
+		logger.Errorw("Find returned error",
+			"error", err,
+		)
+		return matches, errInternal
+	}
+	return matches, nil
+}
+
+// Stats returns the precomputed statistics of set.
+// It returns an error wrapping obj.ErrObjectNotExist if they haven't been computed.
+// The statistics are cached once loaded. Errors aren't, so statistics uploaded
+// later are served without restarting the service.
+func (s *Service) Stats(ctx context.Context, set resultset.ResultSet) (*stats.Stats, error) {
+	if x, ok := s.statistics.Load(set[0]); ok {
+		return x.(*stats.Stats), nil
+	}
+	st, err := stats.LoadObject(ctx, s.bucket.Object(stats.ObjectName(set)))
+	if err != nil {
+		return nil, err
+	}
+	x, _ := s.statistics.LoadOrStore(set[0], st)
+	return x.(*stats.Stats), nil
+}
+
+// CountDigits returns the number of each digit in [start, end) of set
+// with the precomputed histograms. Positions are the same as Get;
+// position 0 is the first digit before the decimal point.
+// It scans at most maxScan digits and returns an error wrapping
+// stats.ErrScanTooLarge if that's not enough.
+func (s *Service) CountDigits(ctx context.Context, set resultset.ResultSet, start, end, maxScan int64) ([]int64, error) {
+	if start < 0 || end > set.TotalDigits()+1 || start > end {
+		return nil, fmt.Errorf("service: invalid range [%d, %d)", start, end)
+	}
+	if end == 0 {
+		return make([]int64, set.Radix()), nil
+	}
+	first := start == 0
+	if first {
+		start++
+	}
+	x, _ := s.histograms.LoadOrStore(set[0], stats.NewHistogramIndex(set, s.bucket))
+	counts, err := x.(*stats.HistogramIndex).Count(ctx, start-1, end-1, maxScan)
+	if err != nil {
+		return nil, err
+	}
+	if first {
+		d, err := strconv.ParseInt(string(set.FirstDigit()), set.Radix(), 0)
+		if err != nil {
+			return nil, fmt.Errorf("service: invalid first digit: %w", err)
+		}
+		counts[d]++
+	}
+	return counts, nil
+}
+
+// Reader is a seekable reader of unpacked digits of a result set.
+// Offset 0 is the first digit after the decimal point and the size is
+// the TotalDigits of the result set.
+type Reader struct {
+	*unpack.UnpackReader
+	rr *resultset.Reader
+}
+
+var _ io.ReadSeekCloser = new(Reader)
+
+// Close closes the underlying readers.
+func (r *Reader) Close() error {
+	return r.rr.Close()
+}
+
+// NewReader returns a new Reader for set.
+// The caller must Close() it after use.
+func (s *Service) NewReader(ctx context.Context, set resultset.ResultSet) *Reader {
+	rr := set.NewReader(ctx, s.bucket)
+	return &Reader{
+		UnpackReader: s.newUnpackReader(ctx, rr),
+		rr:           rr,
+	}
+}
+
+func (s *Service) newUnpackReader(ctx context.Context, rr *resultset.Reader) *unpack.UnpackReader {
+	if s.blocks != nil {
+		return unpack.NewReader(ctx, cached.NewCachedReader(ctx, rr, s.blocks))
+	}
+	return unpack.NewReader(ctx, rr)
+}
+
 // Close closes connections used by the service.
 func (s *Service) Close() error {
 	return s.storage.Close()
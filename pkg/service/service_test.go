@@ -15,15 +15,196 @@
 package service
 
 import (
+	"bytes"
 	"context"
+	"encoding/binary"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/googlecloudplatform/pi-delivery/gen/index"
+	"github.com/googlecloudplatform/pi-delivery/pkg/obj"
+	"github.com/googlecloudplatform/pi-delivery/pkg/obj/gcs"
+	"github.com/googlecloudplatform/pi-delivery/pkg/obj/memory"
+	"github.com/googlecloudplatform/pi-delivery/pkg/resultset"
+	"github.com/googlecloudplatform/pi-delivery/pkg/stats"
+	"github.com/googlecloudplatform/pi-delivery/pkg/ycd"
 	"go.uber.org/zap"
 )
 
+var testDecSet = resultset.ResultSet{
+	{
+		Header: &ycd.Header{
+			FileVersion: "1.1.0",
+			Radix:       10,
+			FirstDigits: "3.14159265358979323846264338327950288419716939937510",
+			BlockSize:   38,
+			BlockID:     0,
+		},
+		Name:             "Pi - Dec - Chudnovsky/Pi - Dec - Chudnovsky - 0.ycd",
+		FirstDigitOffset: 201,
+	},
+}
+
+var testHexSet = resultset.ResultSet{
+	{
+		Header: &ycd.Header{
+			FileVersion: "1.1.0",
+			Radix:       16,
+			FirstDigits: "3.243f6a8885a308d313198a2e03707344a4093822299f31d008",
+			BlockSize:   32,
+			BlockID:     0,
+		},
+		Name:             "Pi - Hex - Chudnovsky/Pi - Hex - Chudnovsky - 0.ycd",
+		FirstDigitOffset: 201,
+	},
+}
+
+var testESet = resultset.ResultSet{
+	{
+		Header: &ycd.Header{
+			FileVersion: "1.1.0",
+			Radix:       10,
+			FirstDigits: "2.71828182845904523536028747135266249775724709369995",
+			BlockSize:   38,
+			BlockID:     0,
+		},
+		Name:             "e - Dec - Binary Splitting/e - Dec - Binary Splitting - 0.ycd",
+		FirstDigitOffset: 201,
+	},
+}
+
+// packWords returns little endian words as in ycd files.
+func packWords(words ...uint64) []byte {
+	buf := make([]byte, 0, len(words)*ycd.WordSize)
+	for _, w := range words {
+		buf = binary.LittleEndian.AppendUint64(buf, w)
+	}
+	return buf
+}
+
+// newTestService returns a Service backed by an in-memory bucket
+// that contains testDecSet, testHexSet and testESet.
+func newTestService(t *testing.T) *Service {
+	t.Helper()
+	client := memory.NewClient()
+	bucket := client.MemoryBucket("test-bucket")
+	for _, v := range []struct {
+		set  resultset.ResultSet
+		data []byte
+	}{
+		{testDecSet, packWords(1415926535897932384, 6264338327950288419)},
+		{testHexSet, packWords(0x243f6a8885a308d3, 0x13198a2e03707344)},
+		{testESet, packWords(7182818284590452353, 6028747135266249775)},
+	} {
+		f := v.set[0]
+		bucket.Put(f.Name, append(make([]byte, f.FirstDigitOffset), v.data...))
+	}
+	s, err := New(client,
+		WithBucketName("test-bucket"),
+		WithResultSets(testDecSet, testHexSet),
+		WithConstant("e", testESet),
+		WithCachePolicy(CacheNone),
+	)
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := s.Close(); err != nil {
+			t.Errorf("Close() failed: %v", err)
+		}
+	})
+	return s
+}
+
+func TestService_New(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	l, _ := zap.NewDevelopment()
+	s := l.Sugar()
+
+	serv := newTestService(t)
+	testCases := []struct {
+		constant string
+		radix    int
+		start, n int64
+		want     string
+	}{
+		{"pi", 10, 0, 1, "3"},
+		{"pi", 10, 1, 1, "1"},
+		{"pi", 10, 0, 39, "314159265358979323846264338327950288419"},
+		{"pi", 10, 30, 20, "950288419"},
+		{"pi", 16, 0, 1, "3"},
+		{"pi", 16, 1, 32, "243f6a8885a308d313198a2e03707344"},
+		{"e", 10, 0, 10, "2718281828"},
+		{"E", 10, 30, 20, "266249775"},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(fmt.Sprintf("Constant %s Radix %d Start %d N %d", tc.constant, tc.radix, tc.start, tc.n), func(t *testing.T) {
+			t.Parallel()
+			set, err := serv.ResultSet(tc.constant, tc.radix)
+			if err != nil {
+				t.Fatalf("ResultSet(%s, %d) failed: %v", tc.constant, tc.radix, err)
+			}
+			got, err := serv.Get(ctx, s, set, tc.start, tc.n)
+			if err != nil {
+				t.Errorf("Get() failed: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, string(got)); diff != "" {
+				t.Errorf("Get() = (-want, +got):\n%s", diff)
+			}
+		})
+	}
+
+	if _, err := serv.ResultSet(resultset.Pi, 8); err == nil {
+		t.Errorf("ResultSet(8) error = got nil, want non-nil")
+	}
+	if _, err := serv.ResultSet("e", 16); err == nil {
+		t.Errorf("ResultSet(e, 16) error = got nil, want non-nil")
+	}
+}
+
+func TestService_NewErrors(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name string
+		opts []Option
+	}{
+		{"empty bucket name", []Option{WithBucketName("")}},
+		{"empty result set", []Option{WithResultSets(resultset.ResultSet{})}},
+		{"invalid result set", []Option{WithResultSets(resultset.ResultSet{
+			{Header: &ycd.Header{Radix: 10, BlockSize: 100, BlockID: 1}, Name: "1.ycd"},
+		})}},
+		{"duplicate manifest sets", []Option{WithManifest(&resultset.Manifest{
+			ResultSets: map[string]resultset.ResultSet{"Decimal": testDecSet, "Decimal2": testDecSet},
+		})}},
+		{"invalid manifest set", []Option{WithManifest(&resultset.Manifest{
+			ResultSets: map[string]resultset.ResultSet{"Decimal": {
+				{Header: &ycd.Header{Radix: 10, BlockSize: 100, BlockID: 1}, Name: "1.ycd"},
+			}},
+		})}},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			if _, err := New(memory.NewClient(), tc.opts...); err == nil {
+				t.Errorf("New() error = got nil, want non-nil")
+			}
+		})
+	}
+	if _, err := New(nil); err == nil {
+		t.Errorf("New(nil) error = got nil, want non-nil")
+	}
+}
+
 func TestService_SimpleGet(t *testing.T) {
 	t.Parallel()
 	ctx := context.Background()
@@ -45,10 +226,19 @@ func TestService_SimpleGet(t *testing.T) {
 
 	l, _ := zap.NewDevelopment()
 	s := l.Sugar()
-	serv := NewService(ctx, s, index.BucketName)
-	if serv == nil {
-		t.Fatal("NewService() got nil, want non-nil")
+	client, err := gcs.NewClient(ctx)
+	if err != nil {
+		t.Fatalf("gcs.NewClient() failed: %v", err)
 	}
+	serv, err := New(client, WithBucketName(index.BucketName))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := serv.Close(); err != nil {
+			t.Errorf("Close() failed: %v", err)
+		}
+	})
 
 	for _, tc := range testCases {
 		tc := tc
@@ -68,3 +258,346 @@ func TestService_SimpleGet(t *testing.T) {
 		})
 	}
 }
+
+func TestService_CachePerService(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	// Two buckets with the same object names must not share cached digits.
+	f := testDecSet[0]
+	words := [][2]uint64{
+		{1415926535897932384, 6264338327950288419},
+		{2718281828459045235, 3602874713526624977},
+	}
+	for _, w := range words {
+		client := memory.NewClient()
+		client.MemoryBucket("test-bucket").Put(f.Name, append(make([]byte, f.FirstDigitOffset), packWords(w[0], w[1])...))
+		serv, err := New(client, WithBucketName("test-bucket"), WithResultSets(testDecSet), WithCachePolicy(CacheFirstBytes))
+		if err != nil {
+			t.Fatalf("New() failed: %v", err)
+		}
+		defer serv.Close()
+
+		got, err := serv.Get(ctx, zap.NewNop().Sugar(), testDecSet, 1, 19)
+		if err != nil {
+			t.Fatalf("Get() failed: %v", err)
+		}
+		if want := fmt.Sprintf("%019d", w[0]); string(got) != want {
+			t.Errorf("Get() = got %s, want %s", got, want)
+		}
+	}
+}
+
+func TestService_WithManifest(t *testing.T) {
+	t.Parallel()
+
+	m := &resultset.Manifest{
+		BucketName: "manifest-bucket",
+		ResultSets: map[string]resultset.ResultSet{
+			"Decimal": testDecSet,
+		},
+	}
+	serv, err := New(memory.NewClient(), WithManifest(m))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	set, err := serv.ResultSet(resultset.Pi, 10)
+	if err != nil {
+		t.Fatalf("ResultSet(10) failed: %v", err)
+	}
+	if diff := cmp.Diff(testDecSet, set); diff != "" {
+		t.Errorf("ResultSet(10) = (-want, +got):\n%s", diff)
+	}
+	if _, err := serv.ResultSet(resultset.Pi, 16); err == nil {
+		t.Errorf("ResultSet(16) error = got nil, want non-nil")
+	}
+}
+
+func TestService_Stream(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	l, _ := zap.NewDevelopment()
+	s := l.Sugar()
+
+	serv := newTestService(t)
+	testCases := []struct {
+		radix    int
+		start, n int64
+		want     string
+	}{
+		{10, 0, 0, ""},
+		{10, 0, 1, "3"},
+		{10, 1, 1, "1"},
+		{10, 0, 39, "314159265358979323846264338327950288419"},
+		{10, 0, 100, "314159265358979323846264338327950288419"},
+		{10, 30, 20, "950288419"},
+		{10, 38, 10, "9"},
+		{10, 39, 10, ""},
+		{16, 1, 32, "243f6a8885a308d313198a2e03707344"},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(fmt.Sprintf("Radix %d Start %d N %d", tc.radix, tc.start, tc.n), func(t *testing.T) {
+			t.Parallel()
+			set, err := serv.ResultSet(resultset.Pi, tc.radix)
+			if err != nil {
+				t.Fatalf("ResultSet(%d) failed: %v", tc.radix, err)
+			}
+			buf := new(bytes.Buffer)
+			n, err := serv.Stream(ctx, s, set, buf, tc.start, tc.n)
+			if err != nil {
+				t.Errorf("Stream() failed: %v", err)
+			}
+			if got, want := n, int64(len(tc.want)); got != want {
+				t.Errorf("Stream() = got %d, want %d", got, want)
+			}
+			if diff := cmp.Diff(tc.want, buf.String()); diff != "" {
+				t.Errorf("Stream() = (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+type errWriter struct{}
+
+var errWrite = errors.New("write error")
+
+func (errWriter) Write(p []byte) (int, error) {
+	return 0, errWrite
+}
+
+func TestService_StreamErrors(t *testing.T) {
+	t.Parallel()
+	l, _ := zap.NewDevelopment()
+	s := l.Sugar()
+
+	serv := newTestService(t)
+	if _, err := serv.Stream(context.Background(), s, testDecSet, errWriter{}, 1, 10); !errors.Is(err, errWrite) {
+		t.Errorf("Stream() error = got %v, want %v", err, errWrite)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := serv.Stream(ctx, s, testDecSet, io.Discard, 1, 10); !errors.Is(err, context.Canceled) {
+		t.Errorf("Stream() error = got %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestService_NewReaderRange(t *testing.T) {
+	t.Parallel()
+
+	serv := newTestService(t)
+	testCases := []struct {
+		method, rng  string
+		code         int
+		contentRange string
+		length       string
+		want         string
+	}{
+		{http.MethodGet, "", http.StatusOK, "", "38", "14159265358979323846264338327950288419"},
+		{http.MethodHead, "", http.StatusOK, "", "38", ""},
+		{http.MethodGet, "bytes=0-4", http.StatusPartialContent, "bytes 0-4/38", "5", "14159"},
+		{http.MethodGet, "bytes=30-", http.StatusPartialContent, "bytes 30-37/38", "8", "50288419"},
+		{http.MethodGet, "bytes=-3", http.StatusPartialContent, "bytes 35-37/38", "3", "419"},
+		{http.MethodGet, "bytes=38-", http.StatusRequestedRangeNotSatisfiable, "bytes */38", "", ""},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(fmt.Sprintf("%s Range %q", tc.method, tc.rng), func(t *testing.T) {
+			t.Parallel()
+			rd := serv.NewReader(context.Background(), testDecSet)
+			defer rd.Close()
+
+			req := httptest.NewRequest(tc.method, "/v1/pi/decimal.txt", nil)
+			if tc.rng != "" {
+				req.Header.Set("Range", tc.rng)
+			}
+			recorder := httptest.NewRecorder()
+			http.ServeContent(recorder, req, "decimal.txt", time.Time{}, rd)
+
+			res := recorder.Result()
+			if got, want := res.StatusCode, tc.code; got != want {
+				t.Errorf("StatusCode = got %d, want %d", got, want)
+			}
+			if got, want := res.Header.Get("Content-Range"), tc.contentRange; got != want {
+				t.Errorf("Content-Range = got %q, want %q", got, want)
+			}
+			if tc.length != "" {
+				if got, want := res.Header.Get("Content-Length"), tc.length; got != want {
+					t.Errorf("Content-Length = got %q, want %q", got, want)
+				}
+			}
+			if tc.code == http.StatusRequestedRangeNotSatisfiable {
+				return
+			}
+			got, err := io.ReadAll(res.Body)
+			if err != nil {
+				t.Errorf("ReadAll() failed: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, string(got)); diff != "" {
+				t.Errorf("Body = (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestService_Search(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	l, _ := zap.NewDevelopment()
+	s := l.Sugar()
+
+	serv := newTestService(t)
+	testCases := []struct {
+		radix    int
+		pattern  string
+		start, n int64
+		limit    int
+		want     []int64
+	}{
+		{10, "3", 0, 39, 0, []int64{0, 9, 15, 17, 24, 25, 27}},
+		{10, "3", 0, 39, 2, []int64{0, 9}},
+		{10, "3", 1, 39, 0, []int64{9, 15, 17, 24, 25, 27}},
+		{10, "314159", 0, 100, 0, []int64{0}},
+		{10, "419", 0, 39, 0, []int64{36}},
+		{10, "419", 0, 38, 0, []int64{}},
+		{10, "9", 38, 10, 0, []int64{38}},
+		{10, "9", 39, 10, 0, []int64{}},
+		{10, "1", 0, 0, 0, []int64{}},
+		{16, "243f", 0, 33, 0, []int64{1}},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(fmt.Sprintf("Radix %d Pattern %s Start %d N %d Limit %d", tc.radix, tc.pattern, tc.start, tc.n, tc.limit), func(t *testing.T) {
+			t.Parallel()
+			set, err := serv.ResultSet(resultset.Pi, tc.radix)
+			if err != nil {
+				t.Fatalf("ResultSet(%d) failed: %v", tc.radix, err)
+			}
+			got, err := serv.Search(ctx, s, set, []byte(tc.pattern), tc.start, tc.n, tc.limit)
+			if err != nil {
+				t.Errorf("Search() failed: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Search() = (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestService_SearchErrors(t *testing.T) {
+	t.Parallel()
+	l, _ := zap.NewDevelopment()
+	s := l.Sugar()
+
+	serv := newTestService(t)
+	if _, err := serv.Search(context.Background(), s, testDecSet, []byte("12a"), 0, 10, 0); err == nil {
+		t.Errorf("Search() error = got nil, want non-nil")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := serv.Search(ctx, s, testDecSet, []byte("1"), 1, 10, 0); !errors.Is(err, context.Canceled) {
+		t.Errorf("Search() error = got %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestService_Stats(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	client := memory.NewClient()
+	bucket := client.MemoryBucket("test-bucket")
+	serv, err := New(client, WithBucketName("test-bucket"), WithResultSets(testDecSet))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer serv.Close()
+
+	if _, err := serv.Stats(ctx, testDecSet); !errors.Is(err, obj.ErrObjectNotExist) {
+		t.Errorf("Stats() error = got %v, want %v", err, obj.ErrObjectNotExist)
+	}
+
+	want, err := stats.New(10, 2, 0)
+	if err != nil {
+		t.Fatalf("stats.New() failed: %v", err)
+	}
+	want.Write([]byte("14159265358979323846264338327950288419"))
+	buf := new(bytes.Buffer)
+	if err := stats.Write(buf, want); err != nil {
+		t.Fatalf("stats.Write() failed: %v", err)
+	}
+	bucket.Put(stats.ObjectName(testDecSet), buf.Bytes())
+
+	got, err := serv.Stats(ctx, testDecSet)
+	if err != nil {
+		t.Fatalf("Stats() failed: %v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Stats() = (-want, +got):\n%s", diff)
+	}
+
+	// The statistics are cached once loaded.
+	bucket.Put(stats.ObjectName(testDecSet), []byte("{"))
+	got, err = serv.Stats(ctx, testDecSet)
+	if err != nil {
+		t.Fatalf("Stats() failed: %v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Stats() = (-want, +got):\n%s", diff)
+	}
+}
+
+func TestService_CountDigits(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	client := memory.NewClient()
+	bucket := client.MemoryBucket("test-bucket")
+	f := testDecSet[0]
+	bucket.Put(f.Name, append(make([]byte, f.FirstDigitOffset), packWords(1415926535897932384, 6264338327950288419)...))
+	serv, err := New(client, WithBucketName("test-bucket"), WithResultSets(testDecSet), WithCachePolicy(CacheNone))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer serv.Close()
+
+	if _, err := serv.CountDigits(ctx, testDecSet, 0, 10, 100); !errors.Is(err, obj.ErrObjectNotExist) {
+		t.Errorf("CountDigits() error = got %v, want %v", err, obj.ErrObjectNotExist)
+	}
+
+	h, err := stats.ComputeHistogram(ctx, testDecSet, bucket, 0, 10, 1)
+	if err != nil {
+		t.Fatalf("ComputeHistogram() failed: %v", err)
+	}
+	buf := new(bytes.Buffer)
+	if err := stats.WriteHistogram(buf, h); err != nil {
+		t.Fatalf("WriteHistogram() failed: %v", err)
+	}
+	bucket.Put(stats.HistogramObjectName(f), buf.Bytes())
+
+	for _, tc := range []struct {
+		start, end int64
+		want       []int64
+	}{
+		{5, 21, []int64{0, 0, 2, 3, 1, 2, 2, 1, 2, 3}}, // 9265358979323846
+		{0, 5, []int64{0, 2, 0, 1, 1, 1, 0, 0, 0, 0}},  // 31415
+		{0, 1, []int64{0, 0, 0, 1, 0, 0, 0, 0, 0, 0}},  // 3
+		{3, 3, []int64{0, 0, 0, 0, 0, 0, 0, 0, 0, 0}},
+	} {
+		got, err := serv.CountDigits(ctx, testDecSet, tc.start, tc.end, 100)
+		if err != nil {
+			t.Errorf("CountDigits(%d, %d) failed: %v", tc.start, tc.end, err)
+			continue
+		}
+		if diff := cmp.Diff(tc.want, got); diff != "" {
+			t.Errorf("CountDigits(%d, %d) = (-want, +got):\n%s", tc.start, tc.end, diff)
+		}
+	}
+	if _, err := serv.CountDigits(ctx, testDecSet, 5, 21, 1); !errors.Is(err, stats.ErrScanTooLarge) {
+		t.Errorf("CountDigits() error = got %v, want %v", err, stats.ErrScanTooLarge)
+	}
+	if _, err := serv.CountDigits(ctx, testDecSet, 0, testDecSet.TotalDigits()+2, 100); err == nil {
+		t.Errorf("CountDigits() error = got nil, want non-nil")
+	}
+}
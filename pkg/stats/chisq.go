@@ -0,0 +1,136 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stats
+
+import "math"
+
+// ChiSquared is the result of Pearson's chi-squared test against
+// the uniform distribution.
+type ChiSquared struct {
+	Statistic float64 `json:"statistic"`
+	// DegreesOfFreedom is the number of categories minus 1.
+	DegreesOfFreedom int `json:"degreesOfFreedom"`
+	// PValue is the probability of a statistic at least as large for
+	// uniformly distributed digits. Small values suggest the digits aren't normal.
+	PValue float64 `json:"pValue"`
+}
+
+// chiSquared tests observed counts against the uniform distribution.
+func chiSquared(observed []int64) ChiSquared {
+	total := int64(0)
+	for _, o := range observed {
+		total += o
+	}
+	r := ChiSquared{DegreesOfFreedom: len(observed) - 1}
+	if total == 0 {
+		r.PValue = 1
+		return r
+	}
+	expected := float64(total) / float64(len(observed))
+	for _, o := range observed {
+		d := float64(o) - expected
+		r.Statistic += d * d / expected
+	}
+	r.PValue = gammaQ(float64(r.DegreesOfFreedom)/2, r.Statistic/2)
+	return r
+}
+
+// DigitChiSquared tests the digit counts.
+func (s *Stats) DigitChiSquared() ChiSquared {
+	return chiSquared(s.Counts)
+}
+
+// NGramChiSquared tests the n-gram counts.
+func (s *Stats) NGramChiSquared() ChiSquared {
+	return chiSquared(s.NGrams)
+}
+
+// gammaQ returns the regularized upper incomplete gamma function Q(a, x).
+// It uses the series expansion for x < a+1 and the continued fraction otherwise.
+func gammaQ(a, x float64) float64 {
+	if x <= 0 {
+		return 1
+	}
+	if x < a+1 {
+		return 1 - gammaPSeries(a, x)
+	}
+	return gammaQContinuedFraction(a, x)
+}
+
+const (
+	gammaEpsilon       = 1e-15
+	gammaMaxIterations = 1000
+)
+
+func gammaPSeries(a, x float64) float64 {
+	lg, _ := math.Lgamma(a)
+	ap := a
+	sum := 1 / a
+	del := sum
+	for i := 0; i < gammaMaxIterations; i++ {
+		ap++
+		del *= x / ap
+		sum += del
+		if math.Abs(del) < math.Abs(sum)*gammaEpsilon {
+			break
+		}
+	}
+	return sum * math.Exp(-x+a*math.Log(x)-lg)
+}
+
+// gammaQContinuedFraction evaluates the continued fraction with the modified Lentz's method.
+func gammaQContinuedFraction(a, x float64) float64 {
+	const tiny = 1e-300
+	lg, _ := math.Lgamma(a)
+	b := x + 1 - a
+	c := 1 / tiny
+	d := 1 / b
+	h := d
+	for i := 1; i <= gammaMaxIterations; i++ {
+		an := -float64(i) * (float64(i) - a)
+		b += 2
+		d = an*d + b
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = b + an/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		del := d * c
+		h *= del
+		if math.Abs(del-1) < gammaEpsilon {
+			break
+		}
+	}
+	return math.Exp(-x+a*math.Log(x)-lg) * h
+}
+
+// Summary is a summary of Stats with test results.
+type Summary struct {
+	*Stats
+	DigitChiSquared ChiSquared `json:"digitChiSquared"`
+	NGramChiSquared ChiSquared `json:"ngramChiSquared"`
+}
+
+// Summary returns the Stats with chi-squared test results.
+func (s *Stats) Summary() *Summary {
+	return &Summary{
+		Stats:           s,
+		DigitChiSquared: s.DigitChiSquared(),
+		NGramChiSquared: s.NGramChiSquared(),
+	}
+}
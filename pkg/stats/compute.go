@@ -0,0 +1,170 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"sync"
+
+	"github.com/googlecloudplatform/pi-delivery/pkg/obj"
+	"github.com/googlecloudplatform/pi-delivery/pkg/resultset"
+	"github.com/googlecloudplatform/pi-delivery/pkg/unpack"
+)
+
+const (
+	// DefaultTaskSize is the default maximum number of digits per task.
+	DefaultTaskSize = 1_000_000_000
+	// readBufferSize is the number of digits read at a time.
+	readBufferSize = 1024 * 1024
+)
+
+// Options configure Compute.
+type Options struct {
+	// NGramLength is the length of n-grams. Default DefaultNGramLength.
+	NGramLength int
+	// Workers is the number of tasks computed concurrently. Default runtime.NumCPU().
+	Workers int
+	// TaskSize is the maximum number of digits per task. Default DefaultTaskSize.
+	TaskSize int64
+}
+
+func (o *Options) withDefaults() Options {
+	r := Options{}
+	if o != nil {
+		r = *o
+	}
+	if r.NGramLength == 0 {
+		r.NGramLength = DefaultNGramLength
+	}
+	if r.Workers <= 0 {
+		r.Workers = runtime.NumCPU()
+	}
+	if r.TaskSize <= 0 {
+		r.TaskSize = DefaultTaskSize
+	}
+	return r
+}
+
+type task struct {
+	start, end int64
+}
+
+// splitTasks splits [start, end) at block boundaries and into tasks of at most size digits.
+func splitTasks(start, end, blockSize, size int64) []task {
+	tasks := make([]task, 0)
+	for start < end {
+		e := (start/blockSize + 1) * blockSize
+		if e > start+size {
+			e = start + size
+		}
+		if e > end {
+			e = end
+		}
+		tasks = append(tasks, task{start, e})
+		start = e
+	}
+	return tasks
+}
+
+// Compute computes the Stats of the digits [start, end) of set,
+// where offset 0 is the first digit after the decimal point.
+// The range is split into tasks that don't cross block boundaries,
+// which are computed in parallel and merged in order.
+func Compute(ctx context.Context, set resultset.ResultSet, bucket obj.Bucket, start, end int64, opts *Options) (*Stats, error) {
+	o := opts.withDefaults()
+	if start < 0 || end > set.TotalDigits() || start > end {
+		return nil, fmt.Errorf("stats: invalid range [%d, %d) for %d digits", start, end, set.TotalDigits())
+	}
+	result, err := New(set.Radix(), o.NGramLength, start)
+	if err != nil {
+		return nil, err
+	}
+
+	tasks := splitTasks(start, end, set.BlockSize(), o.TaskSize)
+	partials := make([]*Stats, len(tasks))
+	errs := make([]error, len(tasks))
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	taskChan := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < o.Workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range taskChan {
+				partials[i], errs[i] = computeTask(ctx, set, bucket, tasks[i], o.NGramLength)
+				if errs[i] != nil {
+					cancel()
+				}
+			}
+		}()
+	}
+	for i := range tasks {
+		select {
+		case taskChan <- i:
+			continue
+		case <-ctx.Done():
+		}
+		break
+	}
+	close(taskChan)
+	wg.Wait()
+
+	// Report the root cause rather than the cancellation it triggered.
+	var firstErr error
+	for _, err := range errs {
+		if err != nil && (firstErr == nil || errors.Is(firstErr, context.Canceled)) {
+			firstErr = err
+		}
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	for _, p := range partials {
+		if err := result.Merge(p); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func computeTask(ctx context.Context, set resultset.ResultSet, bucket obj.Bucket, t task, ngramLength int) (*Stats, error) {
+	s, err := New(set.Radix(), ngramLength, t.start)
+	if err != nil {
+		return nil, err
+	}
+	rr := set.NewReader(ctx, bucket)
+	defer rr.Close()
+	ur := unpack.NewReader(ctx, rr)
+	if _, err := ur.Seek(t.start, io.SeekStart); err != nil {
+		return nil, err
+	}
+	n, err := io.CopyBuffer(s, io.LimitReader(ur, t.end-t.start), make([]byte, readBufferSize))
+	if err != nil {
+		return nil, err
+	}
+	if n != t.end-t.start {
+		return nil, fmt.Errorf("stats: short read at %d: %w", t.start+n, io.ErrUnexpectedEOF)
+	}
+	return s, nil
+}
@@ -0,0 +1,136 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stats
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googlecloudplatform/pi-delivery/pkg/resultset"
+	"github.com/googlecloudplatform/pi-delivery/pkg/tests"
+	"github.com/googlecloudplatform/pi-delivery/pkg/ycd"
+)
+
+const (
+	testBlockSize   = 38
+	testTotalDigits = 100
+)
+
+var testSet = resultset.ResultSet{
+	{
+		Header:           &ycd.Header{Radix: 10, FirstDigits: "3.14", BlockSize: testBlockSize, BlockID: 0},
+		Name:             "test/test - 0.ycd",
+		FirstDigitOffset: 201,
+	},
+	{
+		Header:           &ycd.Header{Radix: 10, FirstDigits: "3.14", BlockSize: testBlockSize, BlockID: 1},
+		Name:             "test/test - 1.ycd",
+		FirstDigitOffset: 201,
+	},
+	{
+		Header:           &ycd.Header{Radix: 10, FirstDigits: "3.14", TotalDigits: testTotalDigits, BlockSize: testBlockSize, BlockID: 2},
+		Name:             "test/test - 2.ycd",
+		FirstDigitOffset: 201,
+	},
+}
+
+// packDecimal packs decimal digits into blocks of little endian words
+// as in ycd files, padding the last word of each block with zeros.
+func packDecimal(digits string, blockSize int) []byte {
+	buf := make([]byte, 0)
+	for b := 0; b < len(digits); b += blockSize {
+		for w := b; w < b+blockSize; w += ycd.DigitsPerWord(10) {
+			word := make([]byte, ycd.DigitsPerWord(10))
+			for i := range word {
+				word[i] = '0'
+				if w+i < len(digits) && w+i < b+blockSize {
+					word[i] = digits[w+i]
+				}
+			}
+			v, _ := strconv.ParseUint(string(word), 10, 64)
+			buf = binary.LittleEndian.AppendUint64(buf, v)
+		}
+	}
+	return buf
+}
+
+func TestCompute(t *testing.T) {
+	t.Parallel()
+
+	digits := genDigits(rand.New(rand.NewSource(1)), testTotalDigits, 10)
+	bucket := tests.NewTestBucket(testSet, packDecimal(digits, testBlockSize))
+
+	testCases := []struct {
+		start, end int64
+		opts       *Options
+	}{
+		{0, testTotalDigits, nil},
+		{0, testTotalDigits, &Options{Workers: 3, TaskSize: 7}},
+		{5, 95, &Options{Workers: 2, TaskSize: 10, NGramLength: 3}},
+		{37, 39, &Options{TaskSize: 1}},
+		{50, 50, nil},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(fmt.Sprintf("[%d, %d) %+v", tc.start, tc.end, tc.opts), func(t *testing.T) {
+			t.Parallel()
+			k := DefaultNGramLength
+			if tc.opts != nil && tc.opts.NGramLength != 0 {
+				k = tc.opts.NGramLength
+			}
+			want := naiveStats(t, digits[tc.start:tc.end], 10, k, tc.start)
+			got, err := Compute(context.Background(), testSet, bucket, tc.start, tc.end, tc.opts)
+			if err != nil {
+				t.Fatalf("Compute() failed: %v", err)
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("Compute() = (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCompute_Errors(t *testing.T) {
+	t.Parallel()
+
+	bucket := tests.NewTestBucket(testSet, packDecimal(genDigits(rand.New(rand.NewSource(1)), testTotalDigits, 10), testBlockSize))
+	for _, r := range [][2]int64{{-1, 10}, {0, testTotalDigits + 1}, {20, 10}} {
+		if _, err := Compute(context.Background(), testSet, bucket, r[0], r[1], nil); err == nil {
+			t.Errorf("Compute(%d, %d) error = got nil, want non-nil", r[0], r[1])
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Compute(ctx, testSet, bucket, 0, testTotalDigits, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("Compute() error = got %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestSplitTasks(t *testing.T) {
+	t.Parallel()
+
+	got := splitTasks(5, 95, 38, 20)
+	want := []task{{5, 25}, {25, 38}, {38, 58}, {58, 76}, {76, 95}}
+	if diff := cmp.Diff(want, got, cmp.AllowUnexported(task{})); diff != "" {
+		t.Errorf("splitTasks() = (-want, +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stats
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+
+	"github.com/goccy/go-json"
+	"github.com/googlecloudplatform/pi-delivery/pkg/obj"
+	"github.com/googlecloudplatform/pi-delivery/pkg/resultset"
+)
+
+// ObjectName returns the name of the object that holds the precomputed
+// Stats of set. It's stats.json in the same directory as the ycd files.
+func ObjectName(set resultset.ResultSet) string {
+	if len(set) == 0 {
+		return ""
+	}
+	return path.Join(path.Dir(set[0].Name), "stats.json")
+}
+
+// Load reads Stats written by Write.
+func Load(r io.Reader) (*Stats, error) {
+	s := new(Stats)
+	if err := json.NewDecoder(r).Decode(s); err != nil {
+		return nil, err
+	}
+	if err := s.validate(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// LoadObject reads Stats from o.
+func LoadObject(ctx context.Context, o obj.Object) (*Stats, error) {
+	rd, err := o.NewRangeReader(ctx, 0, -1)
+	if err != nil {
+		return nil, err
+	}
+	defer rd.Close()
+	return Load(rd)
+}
+
+// Write writes s as JSON with the chi-squared test results.
+func Write(w io.Writer, s *Stats) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s.Summary())
+}
+
+// validate checks the fields of Stats read from a file.
+func (s *Stats) validate() error {
+	e, err := New(s.Radix, s.NGramLength, s.Start)
+	if err != nil {
+		return err
+	}
+	if s.N < 0 || len(s.Counts) != len(e.Counts) || len(s.NGrams) != len(e.NGrams) || len(s.LongestRuns) != len(e.LongestRuns) {
+		return fmt.Errorf("stats: malformed stats for radix %d n-gram %d", s.Radix, s.NGramLength)
+	}
+	n := s.NGramLength - 1
+	if int64(n) > s.N {
+		n = int(s.N)
+	}
+	if len(s.Head) != n || len(s.Tail) != n {
+		return fmt.Errorf("stats: malformed head or tail")
+	}
+	return nil
+}
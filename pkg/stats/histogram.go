@@ -0,0 +1,347 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/goccy/go-json"
+	"github.com/googlecloudplatform/pi-delivery/pkg/obj"
+	"github.com/googlecloudplatform/pi-delivery/pkg/resultset"
+	"github.com/googlecloudplatform/pi-delivery/pkg/unpack"
+	"github.com/googlecloudplatform/pi-delivery/pkg/ycd"
+)
+
+// DefaultGranularity is the default number of digits per histogram bin.
+const DefaultGranularity = 1_000_000_000
+
+// Histogram is the digit counts of a block at a fixed granularity.
+// It's stored as a sidecar object of the ycd file (see HistogramObjectName).
+type Histogram struct {
+	Radix   int   `json:"radix"`
+	BlockID int64 `json:"blockID"`
+	// Start is the offset of the first digit of the block.
+	Start int64 `json:"start"`
+	N     int64 `json:"n"`
+	// Granularity is the number of digits per bin. The last bin may be shorter.
+	Granularity int64 `json:"granularity"`
+	// Counts[i][d] is the number of digit d in the i-th bin,
+	// [Start+i*Granularity, Start+(i+1)*Granularity).
+	Counts [][]int64 `json:"counts"`
+
+	// prefix[i][d] is the number of digit d in the first i bins.
+	prefix [][]int64
+}
+
+// HistogramObjectName returns the name of the histogram sidecar object of f.
+func HistogramObjectName(f *ycd.YCDFile) string {
+	return f.Name + ".hist.json"
+}
+
+// blockRange returns the range of digits in the blockID-th block of set.
+func blockRange(set resultset.ResultSet, blockID int64) (int64, int64) {
+	start := blockID * set.BlockSize()
+	end := start + set.BlockSize()
+	if total := set.TotalDigits(); end > total {
+		end = total
+	}
+	return start, end
+}
+
+// ComputeHistogram computes the Histogram of the blockID-th block of set.
+// Bins are counted in parallel by workers goroutines.
+func ComputeHistogram(ctx context.Context, set resultset.ResultSet, bucket obj.Bucket, blockID, granularity int64, workers int) (*Histogram, error) {
+	if blockID < 0 || blockID >= int64(len(set)) {
+		return nil, fmt.Errorf("stats: block %d out of range", blockID)
+	}
+	if granularity <= 0 {
+		return nil, fmt.Errorf("stats: invalid granularity: %d", granularity)
+	}
+	if workers <= 0 {
+		workers = 1
+	}
+	start, end := blockRange(set, blockID)
+	h := &Histogram{
+		Radix:       set.Radix(),
+		BlockID:     blockID,
+		Start:       start,
+		N:           end - start,
+		Granularity: granularity,
+		Counts:      make([][]int64, (end-start+granularity-1)/granularity),
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	errs := make([]error, len(h.Counts))
+	bins := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range bins {
+				s := start + int64(i)*granularity
+				e := s + granularity
+				if e > end {
+					e = end
+				}
+				h.Counts[i], errs[i] = countDigits(ctx, set, bucket, s, e)
+				if errs[i] != nil {
+					cancel()
+				}
+			}
+		}()
+	}
+	for i := range h.Counts {
+		select {
+		case bins <- i:
+			continue
+		case <-ctx.Done():
+		}
+		break
+	}
+	close(bins)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil && !errors.Is(err, context.Canceled) {
+			return nil, err
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	h.buildPrefix()
+	return h, nil
+}
+
+// countDigits counts each digit in [start, end) of set.
+func countDigits(ctx context.Context, set resultset.ResultSet, bucket obj.Bucket, start, end int64) ([]int64, error) {
+	counts := make([]int64, set.Radix())
+	if start >= end {
+		return counts, nil
+	}
+	rr := set.NewReader(ctx, bucket)
+	defer rr.Close()
+	ur := unpack.NewReader(ctx, rr)
+	if _, err := ur.Seek(start, io.SeekStart); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, readBufferSize)
+	for n := end - start; n > 0; {
+		if int64(len(buf)) > n {
+			buf = buf[:n]
+		}
+		read, err := io.ReadFull(ur, buf)
+		for _, c := range buf[:read] {
+			counts[digitValues[c]]++
+		}
+		n -= int64(read)
+		if err != nil {
+			return nil, fmt.Errorf("stats: read failed at %d: %w", end-n, err)
+		}
+	}
+	return counts, nil
+}
+
+func (h *Histogram) buildPrefix() {
+	h.prefix = make([][]int64, len(h.Counts)+1)
+	h.prefix[0] = make([]int64, h.Radix)
+	for i, c := range h.Counts {
+		p := make([]int64, h.Radix)
+		for d := range p {
+			p[d] = h.prefix[i][d] + c[d]
+		}
+		h.prefix[i+1] = p
+	}
+}
+
+// binStart returns the offset of the i-th bin. i may be len(Counts).
+func (h *Histogram) binStart(i int64) int64 {
+	if s := h.Start + i*h.Granularity; s < h.Start+h.N {
+		return s
+	}
+	return h.Start + h.N
+}
+
+// LoadHistogram reads a Histogram written with WriteHistogram.
+func LoadHistogram(r io.Reader) (*Histogram, error) {
+	h := new(Histogram)
+	if err := json.NewDecoder(r).Decode(h); err != nil {
+		return nil, err
+	}
+	if h.Radix != 10 && h.Radix != 16 || h.Granularity <= 0 || h.N < 0 ||
+		int64(len(h.Counts)) != (h.N+h.Granularity-1)/h.Granularity {
+		return nil, fmt.Errorf("stats: malformed histogram of block %d", h.BlockID)
+	}
+	for _, c := range h.Counts {
+		if len(c) != h.Radix {
+			return nil, fmt.Errorf("stats: malformed histogram of block %d", h.BlockID)
+		}
+	}
+	h.buildPrefix()
+	return h, nil
+}
+
+// WriteHistogram writes h as JSON.
+func WriteHistogram(w io.Writer, h *Histogram) error {
+	return json.NewEncoder(w).Encode(h)
+}
+
+// ErrScanTooLarge is returned by HistogramIndex.Count when answering the query
+// needs to scan more digits than allowed.
+var ErrScanTooLarge = errors.New("stats: range needs too large a scan")
+
+// HistogramIndex answers digit counts of ranges of a result set with the
+// histogram sidecars. Histograms are loaded lazily and cached.
+// It's safe for concurrent use.
+type HistogramIndex struct {
+	set    resultset.ResultSet
+	bucket obj.Bucket
+
+	mu    sync.Mutex
+	hists map[int64]*Histogram
+}
+
+// NewHistogramIndex returns a new HistogramIndex for set stored in bucket.
+func NewHistogramIndex(set resultset.ResultSet, bucket obj.Bucket) *HistogramIndex {
+	return &HistogramIndex{
+		set:    set,
+		bucket: bucket,
+		hists:  make(map[int64]*Histogram),
+	}
+}
+
+func (x *HistogramIndex) histogram(ctx context.Context, blockID int64) (*Histogram, error) {
+	x.mu.Lock()
+	h, ok := x.hists[blockID]
+	x.mu.Unlock()
+	if ok {
+		return h, nil
+	}
+
+	o := x.bucket.Object(HistogramObjectName(x.set[blockID]))
+	rd, err := o.NewRangeReader(ctx, 0, -1)
+	if err != nil {
+		return nil, err
+	}
+	defer rd.Close()
+	h, err = LoadHistogram(rd)
+	if err != nil {
+		return nil, err
+	}
+	if s, e := blockRange(x.set, blockID); h.BlockID != blockID || h.Start != s || h.N != e-s || h.Radix != x.set.Radix() {
+		return nil, fmt.Errorf("stats: histogram doesn't match block %d", blockID)
+	}
+
+	x.mu.Lock()
+	x.hists[blockID] = h
+	x.mu.Unlock()
+	return h, nil
+}
+
+// edge is a part of a range that is counted by scanning digits.
+// The counts are subtracted if negative is true.
+type edge struct {
+	start, end int64
+	negative   bool
+}
+
+// Count returns the number of each digit in [start, end).
+// Whole histogram bins in the range are summed from prefix sums and the rest
+// are scanned, from whichever end of the bin is nearer. It returns
+// ErrScanTooLarge if it needs to scan more than maxScan digits.
+func (x *HistogramIndex) Count(ctx context.Context, start, end, maxScan int64) ([]int64, error) {
+	if start < 0 || end > x.set.TotalDigits() || start > end {
+		return nil, fmt.Errorf("stats: invalid range [%d, %d) for %d digits", start, end, x.set.TotalDigits())
+	}
+	counts := make([]int64, x.set.Radix())
+	edges := make([]edge, 0)
+	blockSize := x.set.BlockSize()
+	for b := start / blockSize; b*blockSize < end; b++ {
+		h, err := x.histogram(ctx, b)
+		if err != nil {
+			return nil, err
+		}
+		s, e := start, end
+		if s < h.Start {
+			s = h.Start
+		}
+		if e > h.Start+h.N {
+			e = h.Start + h.N
+		}
+		// Sum bins [first, last) from the prefix sums, adjusting partial bins at both ends.
+		g := h.Granularity
+		first, last := (s-h.Start)/g, (e-h.Start+g-1)/g
+		if last-first == 1 && (h.binStart(first) < s || e < h.binStart(last)) {
+			// The range is within a bin. Scan it directly.
+			edges = append(edges, edge{s, e, false})
+			continue
+		}
+		for d := range counts {
+			counts[d] += h.prefix[last][d] - h.prefix[first][d]
+		}
+		// Digits in the first bin before s.
+		if bs := h.binStart(first); bs < s {
+			be := h.binStart(first + 1)
+			if s-bs <= be-s {
+				edges = append(edges, edge{bs, s, true})
+			} else {
+				for d := range counts {
+					counts[d] -= h.Counts[first][d]
+				}
+				edges = append(edges, edge{s, be, false})
+			}
+		}
+		// Digits in the last bin after e.
+		if be := h.binStart(last); e < be {
+			bs := h.binStart(last - 1)
+			if be-e <= e-bs {
+				edges = append(edges, edge{e, be, true})
+			} else {
+				for d := range counts {
+					counts[d] -= h.Counts[last-1][d]
+				}
+				edges = append(edges, edge{bs, e, false})
+			}
+		}
+	}
+
+	scan := int64(0)
+	for _, e := range edges {
+		scan += e.end - e.start
+	}
+	if scan > maxScan {
+		return nil, fmt.Errorf("%w: %d > %d", ErrScanTooLarge, scan, maxScan)
+	}
+	for _, e := range edges {
+		c, err := countDigits(ctx, x.set, x.bucket, e.start, e.end)
+		if err != nil {
+			return nil, err
+		}
+		for d := range counts {
+			if e.negative {
+				counts[d] -= c[d]
+			} else {
+				counts[d] += c[d]
+			}
+		}
+	}
+	return counts, nil
+}
@@ -0,0 +1,164 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stats
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googlecloudplatform/pi-delivery/pkg/obj"
+	"github.com/googlecloudplatform/pi-delivery/pkg/obj/memory"
+	"github.com/googlecloudplatform/pi-delivery/pkg/tests"
+)
+
+func naiveCounts(digits string, radix int) []int64 {
+	counts := make([]int64, radix)
+	for i := 0; i < len(digits); i++ {
+		counts[digitValues[digits[i]]]++
+	}
+	return counts
+}
+
+// newHistogramBucket returns a bucket with testSet and its histograms.
+func newHistogramBucket(t *testing.T, digits string, granularity int64) obj.Bucket {
+	t.Helper()
+	bucket := tests.NewTestBucket(testSet, packDecimal(digits, testBlockSize)).(*memory.Bucket)
+	for b := range testSet {
+		h, err := ComputeHistogram(context.Background(), testSet, bucket, int64(b), granularity, 2)
+		if err != nil {
+			t.Fatalf("ComputeHistogram() failed: %v", err)
+		}
+		buf := new(bytes.Buffer)
+		if err := WriteHistogram(buf, h); err != nil {
+			t.Fatalf("WriteHistogram() failed: %v", err)
+		}
+		bucket.Put(HistogramObjectName(testSet[b]), buf.Bytes())
+	}
+	return bucket
+}
+
+func TestComputeHistogram(t *testing.T) {
+	t.Parallel()
+
+	digits := genDigits(rand.New(rand.NewSource(3)), testTotalDigits, 10)
+	bucket := tests.NewTestBucket(testSet, packDecimal(digits, testBlockSize))
+	h, err := ComputeHistogram(context.Background(), testSet, bucket, 2, 10, 3)
+	if err != nil {
+		t.Fatalf("ComputeHistogram() failed: %v", err)
+	}
+	// The last block has 24 digits.
+	if got, want := [3]int64{h.Start, h.N, int64(len(h.Counts))}, [3]int64{76, 24, 3}; got != want {
+		t.Errorf("Start, N, len(Counts) = got %v, want %v", got, want)
+	}
+	for i, c := range h.Counts {
+		e := 76 + 10*(i+1)
+		if e > testTotalDigits {
+			e = testTotalDigits
+		}
+		if diff := cmp.Diff(naiveCounts(digits[76+10*i:e], 10), c); diff != "" {
+			t.Errorf("Counts[%d] = (-want, +got):\n%s", i, diff)
+		}
+	}
+
+	if _, err := ComputeHistogram(context.Background(), testSet, bucket, 3, 10, 1); err == nil {
+		t.Errorf("ComputeHistogram(block 3) error = got nil, want non-nil")
+	}
+	if _, err := ComputeHistogram(context.Background(), testSet, bucket, 0, 0, 1); err == nil {
+		t.Errorf("ComputeHistogram(granularity 0) error = got nil, want non-nil")
+	}
+}
+
+func TestHistogramIndex_Count(t *testing.T) {
+	t.Parallel()
+
+	digits := genDigits(rand.New(rand.NewSource(4)), testTotalDigits, 10)
+	for _, g := range []int64{1, 5, 7, 38, 100} {
+		g := g
+		t.Run(fmt.Sprintf("granularity %d", g), func(t *testing.T) {
+			t.Parallel()
+			x := NewHistogramIndex(testSet, newHistogramBucket(t, digits, g))
+			for s := 0; s <= testTotalDigits; s++ {
+				for _, e := range []int{s, s + 1, s + 4, s + 9, s + 40, testTotalDigits} {
+					if e > testTotalDigits {
+						continue
+					}
+					got, err := x.Count(context.Background(), int64(s), int64(e), math.MaxInt64)
+					if err != nil {
+						t.Fatalf("Count(%d, %d) failed: %v", s, e, err)
+					}
+					if diff := cmp.Diff(naiveCounts(digits[s:e], 10), got); diff != "" {
+						t.Fatalf("Count(%d, %d) = (-want, +got):\n%s", s, e, diff)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestHistogramIndex_WithinBin(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	digits := genDigits(rand.New(rand.NewSource(6)), testTotalDigits, 10)
+	x := NewHistogramIndex(testSet, newHistogramBucket(t, digits, 10))
+	// Ranges within the bin [10, 20) are scanned directly.
+	for _, r := range [][2]int64{{12, 15}, {11, 19}, {18, 19}, {10, 13}, {17, 20}} {
+		s, e := r[0], r[1]
+		got, err := x.Count(ctx, s, e, e-s)
+		if err != nil {
+			t.Errorf("Count(%d, %d) failed: %v", s, e, err)
+			continue
+		}
+		if diff := cmp.Diff(naiveCounts(digits[s:e], 10), got); diff != "" {
+			t.Errorf("Count(%d, %d) = (-want, +got):\n%s", s, e, diff)
+		}
+		if _, err := x.Count(ctx, s, e, e-s-1); !errors.Is(err, ErrScanTooLarge) {
+			t.Errorf("Count(%d, %d) error = got %v, want %v", s, e, err, ErrScanTooLarge)
+		}
+	}
+}
+
+func TestHistogramIndex_Errors(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	digits := genDigits(rand.New(rand.NewSource(5)), testTotalDigits, 10)
+	x := NewHistogramIndex(testSet, newHistogramBucket(t, digits, 10))
+	// Bins are aligned to blocks: [0, 10), [10, 20), [20, 30), [30, 38), [38, 48) ...
+	if _, err := x.Count(ctx, 0, 38, 0); err != nil {
+		t.Errorf("Count(0, 38) failed: %v", err)
+	}
+	if _, err := x.Count(ctx, 2, 38, 2); err != nil {
+		t.Errorf("Count(2, 38) failed: %v", err)
+	}
+	if _, err := x.Count(ctx, 2, 38, 1); !errors.Is(err, ErrScanTooLarge) {
+		t.Errorf("Count(2, 38) error = got %v, want %v", err, ErrScanTooLarge)
+	}
+	if _, err := x.Count(ctx, 0, testTotalDigits+1, 0); err == nil {
+		t.Errorf("Count() error = got nil, want non-nil")
+	}
+
+	// No histograms.
+	x = NewHistogramIndex(testSet, tests.NewTestBucket(testSet, packDecimal(digits, testBlockSize)))
+	if _, err := x.Count(ctx, 0, 10, 0); !errors.Is(err, obj.ErrObjectNotExist) {
+		t.Errorf("Count() error = got %v, want %v", err, obj.ErrObjectNotExist)
+	}
+}
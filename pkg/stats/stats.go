@@ -0,0 +1,290 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package stats computes statistics of digits such as digit counts,
+// n-gram frequencies and the longest runs of each digit.
+// Statistics of adjacent ranges can be merged so they can be computed
+// in parallel and combined.
+package stats
+
+import (
+	"errors"
+	"fmt"
+)
+
+// DefaultNGramLength is the default length of n-grams.
+const DefaultNGramLength = 2
+
+// MaxNGramLength is the maximum length of n-grams.
+// There are radix^n n-grams so this keeps the table at most 16^4 entries.
+const MaxNGramLength = 4
+
+// Run is a run of the same digit.
+type Run struct {
+	Digit int `json:"digit"`
+	// Offset is the offset of the first digit of the run.
+	Offset int64 `json:"offset"`
+	Length int64 `json:"length"`
+}
+
+// Stats are statistics of the digits in [Start, Start+N).
+// Offsets count the first digit after the decimal point as 0.
+// Use New to create one, then feed digits with Write or combine
+// Stats of adjacent ranges with Merge.
+type Stats struct {
+	Radix       int   `json:"radix"`
+	NGramLength int   `json:"ngramLength"`
+	Start       int64 `json:"start"`
+	N           int64 `json:"n"`
+	// Counts are the number of occurrences of each digit.
+	Counts []int64 `json:"counts"`
+	// NGrams are the number of occurrences of each n-gram indexed by the
+	// value of the n-gram in Radix, e.g. "07" is NGrams[7] in decimal.
+	NGrams []int64 `json:"ngrams"`
+	// LongestRuns are the longest runs of each digit. If there are more than
+	// one, the first one is kept. Length is 0 if the digit doesn't occur.
+	LongestRuns []Run `json:"longestRuns"`
+
+	// The following are needed to merge with the adjacent ranges.
+
+	// Head and Tail are the first and the last NGramLength-1 digits.
+	Head string `json:"head"`
+	Tail string `json:"tail"`
+	// PrefixRun and SuffixRun are the runs at the beginning and the end.
+	PrefixRun Run `json:"prefixRun"`
+	SuffixRun Run `json:"suffixRun"`
+}
+
+// New returns empty Stats of radix starting at start.
+func New(radix, ngramLength int, start int64) (*Stats, error) {
+	if radix != 10 && radix != 16 {
+		return nil, fmt.Errorf("stats: unsupported radix: %d", radix)
+	}
+	if ngramLength < 1 || ngramLength > MaxNGramLength {
+		return nil, fmt.Errorf("stats: n-gram length must be between 1 and %d: %d", MaxNGramLength, ngramLength)
+	}
+	s := &Stats{
+		Radix:       radix,
+		NGramLength: ngramLength,
+		Start:       start,
+		Counts:      make([]int64, radix),
+		NGrams:      make([]int64, pow(radix, ngramLength)),
+		LongestRuns: make([]Run, radix),
+	}
+	for d := range s.LongestRuns {
+		s.LongestRuns[d].Digit = d
+	}
+	return s, nil
+}
+
+func pow(x, n int) int {
+	r := 1
+	for i := 0; i < n; i++ {
+		r *= x
+	}
+	return r
+}
+
+// digitValues maps digits to their values and other bytes to -1.
+var digitValues = func() (t [256]int8) {
+	for i := range t {
+		t[i] = -1
+	}
+	for c := '0'; c <= '9'; c++ {
+		t[c] = int8(c - '0')
+	}
+	for c := 'a'; c <= 'f'; c++ {
+		t[c] = int8(c - 'a' + 10)
+	}
+	return
+}()
+
+// End returns the offset of the digit after the range.
+func (s *Stats) End() int64 {
+	return s.Start + s.N
+}
+
+// Write adds the digits in p, which immediately follow the digits already added.
+// It returns an error if p contains a byte that isn't a digit of Radix.
+func (s *Stats) Write(p []byte) (int, error) {
+	k := s.NGramLength
+	mod := len(s.NGrams)
+	// The value of the last k-1 digits.
+	g := s.tailValue()
+	for i, c := range p {
+		d := int(digitValues[c])
+		if d < 0 || d >= s.Radix {
+			s.finishWrite(p[:i])
+			return i, fmt.Errorf("stats: invalid digit %q at %d", c, s.End())
+		}
+		pos := s.N
+		s.N++
+		s.Counts[d]++
+
+		g = (g*s.Radix + d) % mod
+		if s.N >= int64(k) {
+			s.NGrams[g]++
+		}
+
+		if pos > 0 && s.SuffixRun.Digit == d {
+			s.SuffixRun.Length++
+		} else {
+			s.SuffixRun = Run{Digit: d, Offset: s.Start + pos, Length: 1}
+		}
+		if pos == 0 {
+			s.PrefixRun = s.SuffixRun
+		} else if s.PrefixRun.Length == pos && s.PrefixRun.Digit == d {
+			s.PrefixRun.Length++
+		}
+		if s.SuffixRun.Length > s.LongestRuns[d].Length {
+			s.LongestRuns[d] = s.SuffixRun
+		}
+	}
+	s.finishWrite(p)
+	return len(p), nil
+}
+
+// finishWrite updates Head and Tail after p is written.
+func (s *Stats) finishWrite(p []byte) {
+	n := s.NGramLength - 1
+	head, tail := p, p
+	if len(p) > n {
+		head, tail = p[:n], p[len(p)-n:]
+	}
+	s.Head, s.Tail = mergeEdges(s.Head, s.Tail, string(head), string(tail), n)
+}
+
+// mergeEdges returns the first and the last n digits of the concatenation of
+// two ranges given their first and last n digits.
+// If a range is shorter than n, its head and tail are the whole range.
+func mergeEdges(aHead, aTail, bHead, bTail string, n int) (string, string) {
+	head := aHead
+	if len(head) < n {
+		head += bHead
+	}
+	if len(head) > n {
+		head = head[:n]
+	}
+	tail := bTail
+	if len(tail) < n {
+		tail = aTail + tail
+	}
+	if len(tail) > n {
+		tail = tail[len(tail)-n:]
+	}
+	return head, tail
+}
+
+// tailValue returns the value of Tail in Radix.
+func (s *Stats) tailValue() int {
+	g := 0
+	for i := 0; i < len(s.Tail); i++ {
+		g = g*s.Radix + int(digitValues[s.Tail[i]])
+	}
+	return g
+}
+
+// ErrNotAdjacent is returned by Merge when the ranges aren't adjacent.
+var ErrNotAdjacent = errors.New("stats: ranges are not adjacent")
+
+// Merge adds the statistics of o, whose range must immediately follow s.
+func (s *Stats) Merge(o *Stats) error {
+	if s.Radix != o.Radix || s.NGramLength != o.NGramLength {
+		return fmt.Errorf("stats: can't merge radix %d n-gram %d with radix %d n-gram %d",
+			o.Radix, o.NGramLength, s.Radix, s.NGramLength)
+	}
+	if s.End() != o.Start {
+		return fmt.Errorf("%w: [%d, %d) and [%d, %d)", ErrNotAdjacent, s.Start, s.End(), o.Start, o.End())
+	}
+	if o.N == 0 {
+		return nil
+	}
+	if s.N == 0 {
+		start := s.Start
+		*s = *o.clone()
+		s.Start = start
+		return nil
+	}
+
+	for d := range s.Counts {
+		s.Counts[d] += o.Counts[d]
+	}
+	for i := range s.NGrams {
+		s.NGrams[i] += o.NGrams[i]
+	}
+	// N-grams across the boundary. Every n-gram in Tail+Head crosses it
+	// since both are shorter than n.
+	edge := s.Tail + o.Head
+	mod := len(s.NGrams)
+	g := 0
+	for i := 0; i < len(edge); i++ {
+		g = (g*s.Radix + int(digitValues[edge[i]])) % mod
+		if i >= s.NGramLength-1 {
+			s.NGrams[g]++
+		}
+	}
+
+	// The run across the boundary.
+	var cross Run
+	if s.SuffixRun.Digit == o.PrefixRun.Digit {
+		cross = Run{
+			Digit:  s.SuffixRun.Digit,
+			Offset: s.SuffixRun.Offset,
+			Length: s.SuffixRun.Length + o.PrefixRun.Length,
+		}
+	}
+	for d := range s.LongestRuns {
+		if o.LongestRuns[d].Length > s.LongestRuns[d].Length {
+			s.LongestRuns[d] = o.LongestRuns[d]
+		}
+	}
+	if cross.Length > 0 {
+		cur := s.LongestRuns[cross.Digit]
+		if cross.Length > cur.Length || cross.Length == cur.Length && cross.Offset < cur.Offset {
+			s.LongestRuns[cross.Digit] = cross
+		}
+		if s.PrefixRun.Length == s.N {
+			s.PrefixRun.Length += o.PrefixRun.Length
+		}
+		if o.SuffixRun.Length == o.N {
+			s.SuffixRun.Length += o.N
+		} else {
+			s.SuffixRun = o.SuffixRun
+		}
+	} else {
+		s.SuffixRun = o.SuffixRun
+	}
+
+	s.Head, s.Tail = mergeEdges(s.Head, s.Tail, o.Head, o.Tail, s.NGramLength-1)
+	s.N += o.N
+	return nil
+}
+
+func (s *Stats) clone() *Stats {
+	c := *s
+	c.Counts = append([]int64(nil), s.Counts...)
+	c.NGrams = append([]int64(nil), s.NGrams...)
+	c.LongestRuns = append([]Run(nil), s.LongestRuns...)
+	return &c
+}
+
+// NGram returns the string representation of the i-th n-gram.
+func (s *Stats) NGram(i int) string {
+	b := make([]byte, s.NGramLength)
+	for j := len(b) - 1; j >= 0; j-- {
+		b[j] = "0123456789abcdef"[i%s.Radix]
+		i /= s.Radix
+	}
+	return string(b)
+}
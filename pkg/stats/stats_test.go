@@ -0,0 +1,240 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stats
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// naiveStats computes Stats of digits without Write or Merge.
+func naiveStats(t *testing.T, digits string, radix, k int, start int64) *Stats {
+	t.Helper()
+	s, err := New(radix, k, start)
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	s.N = int64(len(digits))
+	for i := 0; i < len(digits); i++ {
+		s.Counts[digitValues[digits[i]]]++
+		if i+k <= len(digits) {
+			g := 0
+			for j := i; j < i+k; j++ {
+				g = g*radix + int(digitValues[digits[j]])
+			}
+			s.NGrams[g]++
+		}
+		j := i
+		for j < len(digits) && digits[j] == digits[i] {
+			j++
+		}
+		d := int(digitValues[digits[i]])
+		if int64(j-i) > s.LongestRuns[d].Length {
+			s.LongestRuns[d] = Run{Digit: d, Offset: start + int64(i), Length: int64(j - i)}
+		}
+		if i == 0 {
+			s.PrefixRun = Run{Digit: d, Offset: start, Length: int64(j)}
+		}
+		if j == len(digits) && (i == 0 || digits[i-1] != digits[i]) {
+			s.SuffixRun = Run{Digit: d, Offset: start + int64(i), Length: int64(j - i)}
+		}
+	}
+	n := k - 1
+	if n > len(digits) {
+		n = len(digits)
+	}
+	s.Head = digits[:n]
+	s.Tail = digits[len(digits)-n:]
+	return s
+}
+
+// genDigits returns n random digits with long runs.
+func genDigits(r *rand.Rand, n, radix int) string {
+	var b strings.Builder
+	for b.Len() < n {
+		c := "0123456789abcdef"[r.Intn(radix)]
+		b.WriteString(strings.Repeat(string(c), 1+r.Intn(4)))
+	}
+	return b.String()[:n]
+}
+
+func TestStats_WriteMerge(t *testing.T) {
+	t.Parallel()
+
+	r := rand.New(rand.NewSource(42))
+	for _, radix := range []int{10, 16} {
+		for k := 1; k <= 3; k++ {
+			radix, k := radix, k
+			digits := genDigits(r, 200, radix)
+			t.Run(fmt.Sprintf("Radix %d NGram %d", radix, k), func(t *testing.T) {
+				t.Parallel()
+				want := naiveStats(t, digits, radix, k, 1000)
+
+				// Write in pieces.
+				got, _ := New(radix, k, 1000)
+				for i := 0; i < len(digits); i += 7 {
+					end := i + 7
+					if end > len(digits) {
+						end = len(digits)
+					}
+					if _, err := got.Write([]byte(digits[i:end])); err != nil {
+						t.Fatalf("Write() failed: %v", err)
+					}
+				}
+				if diff := cmp.Diff(want, got); diff != "" {
+					t.Errorf("Write() = (-want, +got):\n%s", diff)
+				}
+
+				// Merge partials split at various positions, including empty ones.
+				for split := 0; split <= len(digits); split += 1 + split%7 {
+					a, _ := New(radix, k, 1000)
+					a.Write([]byte(digits[:split]))
+					b, _ := New(radix, k, 1000+int64(split))
+					b.Write([]byte(digits[split:]))
+					if err := a.Merge(b); err != nil {
+						t.Fatalf("Merge() failed: %v", err)
+					}
+					if diff := cmp.Diff(want, a); diff != "" {
+						t.Fatalf("Merge(split %d) = (-want, +got):\n%s", split, diff)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestStats_MergeShortRanges(t *testing.T) {
+	t.Parallel()
+
+	// Merging one digit at a time exercises ranges shorter than the n-grams
+	// and runs that span several partials.
+	digits := "1112222223333333333345999999"
+	want := naiveStats(t, digits, 10, 4, 0)
+	got, _ := New(10, 4, 0)
+	for i := range digits {
+		p, _ := New(10, 4, int64(i))
+		p.Write([]byte{digits[i]})
+		if err := got.Merge(p); err != nil {
+			t.Fatalf("Merge() failed: %v", err)
+		}
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Merge() = (-want, +got):\n%s", diff)
+	}
+}
+
+func TestStats_Errors(t *testing.T) {
+	t.Parallel()
+
+	if _, err := New(8, 2, 0); err == nil {
+		t.Errorf("New(8) error = got nil, want non-nil")
+	}
+	if _, err := New(10, MaxNGramLength+1, 0); err == nil {
+		t.Errorf("New(n-gram %d) error = got nil, want non-nil", MaxNGramLength+1)
+	}
+
+	s, _ := New(10, 2, 0)
+	if n, err := s.Write([]byte("123a4")); err == nil || n != 3 {
+		t.Errorf("Write() = got (%d, %v), want (3, non-nil)", n, err)
+	}
+	if got, want := s.Tail, "3"; got != want {
+		t.Errorf("Tail = got %q, want %q", got, want)
+	}
+
+	o, _ := New(10, 2, 4)
+	if err := s.Merge(o); err == nil {
+		t.Errorf("Merge() error = got nil, want non-nil")
+	}
+	o, _ = New(16, 2, 3)
+	if err := s.Merge(o); err == nil {
+		t.Errorf("Merge() error = got nil, want non-nil")
+	}
+}
+
+func TestChiSquared(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		observed  []int64
+		statistic float64
+		pValue    float64
+	}{
+		{[]int64{10, 10, 10, 10}, 0, 1},
+		{[]int64{0, 0, 0}, 0, 1},
+		// Critical values of the chi-squared distribution.
+		{[]int64{60, 40}, 4, 0.0455},
+		{[]int64{120, 80, 100, 100, 100, 100, 100, 100, 100, 100}, 8, 0.5341},
+	}
+	for _, tc := range testCases {
+		got := chiSquared(tc.observed)
+		if got.DegreesOfFreedom != len(tc.observed)-1 {
+			t.Errorf("chiSquared(%v).DegreesOfFreedom = got %d, want %d", tc.observed, got.DegreesOfFreedom, len(tc.observed)-1)
+		}
+		if math.Abs(got.Statistic-tc.statistic) > 1e-9 {
+			t.Errorf("chiSquared(%v).Statistic = got %f, want %f", tc.observed, got.Statistic, tc.statistic)
+		}
+		if math.Abs(got.PValue-tc.pValue) > 1e-4 {
+			t.Errorf("chiSquared(%v).PValue = got %f, want %f", tc.observed, got.PValue, tc.pValue)
+		}
+	}
+
+	// Large statistics use the continued fraction.
+	if got := gammaQ(4.5, 16.919/2); math.Abs(got-0.05) > 1e-4 {
+		t.Errorf("gammaQ() = got %f, want 0.05", got)
+	}
+}
+
+func TestStats_NGram(t *testing.T) {
+	t.Parallel()
+
+	s, _ := New(16, 3, 0)
+	if got, want := s.NGram(0xa07), "a07"; got != want {
+		t.Errorf("NGram() = got %s, want %s", got, want)
+	}
+}
+
+func TestStats_WriteLoad(t *testing.T) {
+	t.Parallel()
+
+	want := naiveStats(t, genDigits(rand.New(rand.NewSource(7)), 100, 16), 16, 3, 10)
+	buf := new(bytes.Buffer)
+	if err := Write(buf, want); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	got, err := Load(buf)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Load() = (-want, +got):\n%s", diff)
+	}
+
+	for _, s := range []string{
+		`{"radix":8,"ngramLength":2}`,
+		`{"radix":10,"ngramLength":2,"counts":[1,2]}`,
+		`{"radix":10,"ngramLength":1,"n":1,"counts":[0,1,0,0,0,0,0,0,0,0],"ngrams":[0,1,0,0,0,0,0,0,0,0],"longestRuns":[{},{},{},{},{},{},{},{},{},{}],"head":"1"}`,
+		`not json`,
+	} {
+		if _, err := Load(strings.NewReader(s)); err == nil {
+			t.Errorf("Load(%s) error = got nil, want non-nil", s)
+		}
+	}
+}
@@ -15,53 +15,11 @@
 package tests
 
 import (
-	"bytes"
-	"context"
-	"io"
-
-	"github.com/golang/mock/gomock"
 	"github.com/googlecloudplatform/pi-delivery/pkg/obj"
-	mock_obj "github.com/googlecloudplatform/pi-delivery/pkg/obj/mocks"
+	"github.com/googlecloudplatform/pi-delivery/pkg/obj/memory"
 	"github.com/googlecloudplatform/pi-delivery/pkg/resultset"
 )
 
-// NewTestReader returns an io.ReadCloser for buf [off, off+length]
-// based on the offsets in set.
-func NewTestReader(set resultset.ResultSet, idx int, buf []byte, off, length int64) (io.ReadCloser, error) {
-	if idx >= len(set) {
-		return nil, io.EOF
-	}
-	off -= int64(set[idx].FirstDigitOffset)
-	if off >= set[idx].BlockByteLength() {
-		return nil, io.EOF
-	}
-
-	base := int64(idx) * set.BlockByteLength()
-	next := int64(idx+1) * set.BlockByteLength()
-	if length < 0 {
-		return io.NopCloser(
-			bytes.NewReader(
-				buf[off+base : next],
-			),
-		), nil
-	}
-	end := off + length
-	if end > set.BlockByteLength() {
-		end = set.BlockByteLength()
-	}
-	if end+base > int64(len(buf)) {
-		end = int64(len(buf)) - base
-	}
-	if off+base == end+base {
-		// zero bytes remaining
-		return nil, io.EOF
-	}
-	return io.NopCloser(
-		bytes.NewReader(
-			buf[off+base : end+base]),
-	), nil
-}
-
 // GenTestByteSeq returns a byte slice for tests with length n.
 func GenTestByteSeq(n int) []byte {
 	buf := make([]byte, n)
@@ -71,22 +29,24 @@ func GenTestByteSeq(n int) []byte {
 	return buf
 }
 
-// NewMockBucket returns a mock bucket for set that returns testBuf data.
-func NewMockBucket(ctx context.Context, ctrl *gomock.Controller, set resultset.ResultSet, testBuf []byte) obj.Bucket {
-	bucket := mock_obj.NewMockBucket(ctrl)
+// NewTestBucket returns an in-memory bucket for set that contains testBuf data.
+// testBuf is split into blocks of set.BlockByteLength() bytes and each block
+// is stored after FirstDigitOffset bytes of padding in place of the header.
+// The last block can be shorter than the others.
+func NewTestBucket(set resultset.ResultSet, testBuf []byte) obj.Bucket {
+	bucket := memory.NewBucket()
 	for i, f := range set {
-		i := i
-		obj := mock_obj.NewMockObject(ctrl)
-		obj.EXPECT().NewRangeReader(
-			gomock.AssignableToTypeOf(ctx),
-			gomock.Any(),
-			gomock.Any(),
-		).DoAndReturn(
-			func(ctx context.Context, off, length int64) (io.ReadCloser, error) {
-				return NewTestReader(set, i, testBuf, off, length)
-			},
-		).AnyTimes()
-		bucket.EXPECT().Object(f.Name).Return(obj).AnyTimes()
+		start := int64(i) * set.BlockByteLength()
+		end := start + set.BlockByteLength()
+		if start > int64(len(testBuf)) {
+			start = int64(len(testBuf))
+		}
+		if end > int64(len(testBuf)) {
+			end = int64(len(testBuf))
+		}
+		data := make([]byte, int64(f.FirstDigitOffset)+end-start)
+		copy(data[f.FirstDigitOffset:], testBuf[start:end])
+		bucket.Put(f.Name, data)
 	}
 	return bucket
 }
@@ -28,6 +28,8 @@ import (
 type UpstreamReader interface {
 	io.ReadSeeker
 	io.ReaderAt
+	// ReadAtContext is ReadAt with a context.
+	ReadAtContext(ctx context.Context, p []byte, off int64) (int, error)
 	// Result returns the upstream result set.
 	ResultSet() resultset.ResultSet
 }
@@ -37,6 +39,7 @@ type UpstreamReader interface {
 // Note the first offset is still the first digit after the decimal point as in
 // the packed format.
 type UnpackReader struct {
+	ctx         context.Context
 	radix       int
 	off         int64
 	totalDigits int64
@@ -54,6 +57,7 @@ var ErrNotFullWord = errors.New("read bytes are not full words")
 // NewReader returns a new UnpackReader for UpstreamReader rd
 func NewReader(ctx context.Context, rd UpstreamReader) *UnpackReader {
 	return &UnpackReader{
+		ctx:         ctx,
 		radix:       rd.ResultSet().Radix(),
 		totalDigits: rd.ResultSet().TotalDigits(),
 		blockSize:   rd.ResultSet().BlockSize(),
@@ -66,6 +70,11 @@ func NewReader(ctx context.Context, rd UpstreamReader) *UnpackReader {
 // Note that YCD files starts at the second digit after the decimal point
 // so we'll treat the 0-th digit specifically.
 func (r *UnpackReader) ReadAt(p []byte, off int64) (int, error) {
+	return r.ReadAtContext(r.ctx, p, off)
+}
+
+// ReadAtContext is the same as ReadAt but uses ctx for reads from the upstream.
+func (r *UnpackReader) ReadAtContext(ctx context.Context, p []byte, off int64) (int, error) {
 	if len(p) == 0 {
 		return 0, nil
 	}
@@ -75,7 +84,7 @@ func (r *UnpackReader) ReadAt(p []byte, off int64) (int, error) {
 
 	start, n, pre, _ := ToPackedOffsets(off, r.blockSize, int64(len(p)), ycd.DigitsPerWord(r.radix))
 	packed := make([]byte, n)
-	read, err := r.rd.ReadAt(packed, start)
+	read, err := r.rd.ReadAtContext(ctx, packed, start)
 	if read == 0 {
 		return 0, err
 	}
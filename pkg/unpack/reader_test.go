@@ -21,7 +21,6 @@ import (
 	"testing"
 	"testing/iotest"
 
-	"github.com/golang/mock/gomock"
 	"github.com/google/go-cmp/cmp"
 	"github.com/googlecloudplatform/pi-delivery/pkg/cached"
 	"github.com/googlecloudplatform/pi-delivery/pkg/resultset"
@@ -77,9 +76,8 @@ func TestUnpack_SingleBlock(t *testing.T) {
 		tc := tc
 		t.Run(fmt.Sprintf("Radix %d", tc.set.Radix()), func(t *testing.T) {
 			t.Parallel()
-			mockCtrl := gomock.NewController(t)
 			ctx := context.Background()
-			bucket := tests.NewMockBucket(ctx, mockCtrl, tc.set, tc.testBytes)
+			bucket := tests.NewTestBucket(tc.set, tc.testBytes)
 
 			ur := tc.set.NewReader(ctx, bucket)
 			t.Cleanup(func() {
@@ -88,7 +86,7 @@ func TestUnpack_SingleBlock(t *testing.T) {
 				}
 			})
 
-			rd := NewReader(ctx, cached.NewCachedReader(ctx, ur))
+			rd := NewReader(ctx, cached.NewCachedReader(ctx, ur, cached.NewCache()))
 			n, err := rd.Read(nil)
 			if err != nil {
 				t.Errorf("Read(nil) failed: %v", err)
@@ -220,10 +218,9 @@ func TestUnpack_ReaderWithBoundaries(t *testing.T) {
 			FirstDigitOffset: 201,
 		},
 	}
-	mockCtrl := gomock.NewController(t)
 	ctx := context.Background()
 
-	bucket := tests.NewMockBucket(ctx, mockCtrl, testSet, testDecMultipleBlocks)
+	bucket := tests.NewTestBucket(testSet, testDecMultipleBlocks)
 
 	ur := testSet.NewReader(ctx, bucket)
 	t.Cleanup(func() {
@@ -28,15 +28,72 @@ var ErrBufferTooSmall error = errors.New("Unpack: destination buffer is too smal
 var ErrInvalidWord error = errors.New("Unpack: invalid word")
 
 const (
-	zeros    = "0000000000000000000"
 	WordSize = ycd.WordSize
+
+	// maxDecimalWord is 10^19, the smallest word with 20 decimal digits.
+	maxDecimalWord = 10_000_000_000_000_000_000
 )
 
-func copyWithZero(dst []byte, s string, nz int) int {
-	return copy(dst, zeros[:nz]) + copy(dst[nz:], s)
+// decimalPairs holds the two-digit decimal strings from "00" to "99" back to back.
+const decimalPairs = "0001020304050607080910111213141516171819" +
+	"2021222324252627282930313233343536373839" +
+	"4041424344454647484950515253545556575859" +
+	"6061626364656667686970717273747576777879" +
+	"8081828384858687888990919293949596979899"
+
+const hexDigits = "0123456789abcdef"
+
+// putDecimal4 writes v < 10^4 as 4 decimal digits to dst.
+func putDecimal4(dst []byte, v uint32) {
+	_ = dst[3]
+	hi, lo := v/100*2, v%100*2
+	dst[0], dst[1] = decimalPairs[hi], decimalPairs[hi+1]
+	dst[2], dst[3] = decimalPairs[lo], decimalPairs[lo+1]
+}
+
+// putDecimal8 writes v < 10^8 as 8 decimal digits to dst.
+func putDecimal8(dst []byte, v uint32) {
+	_ = dst[7]
+	putDecimal4(dst[0:4], v/10_000)
+	putDecimal4(dst[4:8], v%10_000)
+}
+
+// putWord writes the dpw digits of w in radix to dst, which must hold at least dpw bytes,
+// with leading zeros. It doesn't write anything and returns false if w isn't a valid word.
+func putWord(dst []byte, w uint64, radix int) bool {
+	switch radix {
+	case 10:
+		if !ValidWord(w, radix) {
+			return false
+		}
+		_ = dst[18]
+		// Split into 3 + 8 + 8 digits so that the rest is in 32 bits.
+		hi, lo := uint32(w/1e16), w%1e16
+		dst[0] = byte('0' + hi/100)
+		hi = hi % 100 * 2
+		dst[1], dst[2] = decimalPairs[hi], decimalPairs[hi+1]
+		putDecimal8(dst[3:11], uint32(lo/1e8))
+		putDecimal8(dst[11:19], uint32(lo%1e8))
+	case 16:
+		_ = dst[15]
+		for i := 14; i >= 0; i -= 2 {
+			dst[i], dst[i+1] = hexDigits[w>>4&0xf], hexDigits[w&0xf]
+			w >>= 8
+		}
+	default:
+		panic("unknown radix")
+	}
+	return true
+}
+
+func invalidWordError(word []byte, radix int) error {
+	return fmt.Errorf("%w: word = %16x, unpacked = %s", ErrInvalidWord,
+		word, strconv.FormatUint(binary.LittleEndian.Uint64(word), radix))
 }
 
 // UnpackBlock reads packed digits from packed and writes unpacked strings to unpacked.
+// pre digits are skipped at the start of the first word. The digits of the last word
+// are truncated to fit in unpacked.
 func UnpackBlock(unpacked, packed []byte, radix, pre int) (int, error) {
 	if len(packed) == 0 || len(unpacked) == 0 {
 		return 0, nil
@@ -50,26 +107,17 @@ func UnpackBlock(unpacked, packed []byte, radix, pre int) (int, error) {
 			ErrBufferTooSmall, unpackedLen, len(unpacked))
 	}
 
+	// Words that don't fit in unpacked are written to buf first.
+	var buf [19]byte
+
 	// Unpack the first word with pre.
-	// Copy dpw-pre bytes.
-	s := strconv.FormatUint(binary.LittleEndian.Uint64(packed), radix)
-	nz := dpw - len(s)
-	if nz < 0 {
-		return 0, fmt.Errorf("%w: word = %16x, unpacked = %s",
-			ErrInvalidWord, packed[:WordSize], s)
+	if !putWord(buf[:], binary.LittleEndian.Uint64(packed), radix) {
+		return 0, invalidWordError(packed[:WordSize], radix)
 	}
-	nzNeeded := nz - pre
-	if nzNeeded < 0 {
-		nzNeeded = 0
-	}
-	n := copy(unpacked, zeros[:nzNeeded])
-	if n < dpw-pre && n < len(unpacked) {
-		if nz < pre {
-			n += copy(unpacked[n:], s[pre-nz:dpw-pre-n+(pre-nz)])
-		} else {
-			n += copy(unpacked[n:], s[:dpw-pre-n])
-		}
+	if pre > dpw {
+		pre = dpw
 	}
+	n := copy(unpacked, buf[pre:dpw])
 
 	if len(packed) == WordSize {
 		return n, nil
@@ -77,29 +125,43 @@ func UnpackBlock(unpacked, packed []byte, radix, pre int) (int, error) {
 
 	// Process until the second last word.
 	for i := WordSize; i < len(packed)-WordSize; i += WordSize {
-		s := strconv.FormatUint(binary.LittleEndian.Uint64(packed[i:]), radix)
-		nz := dpw - len(s)
-		if nz < 0 {
-			return n, fmt.Errorf("%w: word = %16x, unpacked = %s", ErrInvalidWord,
-				packed[i:i+WordSize], s)
+		w := binary.LittleEndian.Uint64(packed[i:])
+		if len(unpacked)-n >= dpw {
+			if !putWord(unpacked[n:], w, radix) {
+				return n, invalidWordError(packed[i:i+WordSize], radix)
+			}
+			n += dpw
+			continue
+		}
+		if !putWord(buf[:], w, radix) {
+			return n, invalidWordError(packed[i:i+WordSize], radix)
 		}
-		n += copyWithZero(unpacked[n:], s, nz)
+		n += copy(unpacked[n:], buf[:dpw])
 	}
 
 	// Process the last word with post.
-	s = strconv.FormatUint(binary.LittleEndian.Uint64(packed[len(packed)-WordSize:]), radix)
-	nz = dpw - len(s)
-	if nz < 0 {
-		return n, fmt.Errorf("%w: word = %16x, unpacked = %s", ErrInvalidWord,
-			packed[len(packed)-WordSize:], s)
-	}
-	n += copy(unpacked[n:], zeros[:nz])
-	if n < len(unpacked) {
-		n += copy(unpacked[n:], s)
+	last := packed[len(packed)-WordSize:]
+	if !putWord(buf[:], binary.LittleEndian.Uint64(last), radix) {
+		return n, invalidWordError(last, radix)
 	}
+	n += copy(unpacked[n:], buf[:dpw])
 	return n, nil
 }
 
+// ValidWord reports whether w is a valid packed word in radix, i.e. whether
+// UnpackBlock unpacks it without ErrInvalidWord.
+// A decimal word must hold at most 19 digits. Any hexadecimal word is valid.
+func ValidWord(w uint64, radix int) bool {
+	switch radix {
+	case 10:
+		return w < maxDecimalWord
+	case 16:
+		return true
+	default:
+		return false
+	}
+}
+
 // UnpackedLen returns a number of bytes to store
 // an unpacked sequence for n bytes of packed bytes.
 func UnpackedLen(n int64, radix int) int64 {
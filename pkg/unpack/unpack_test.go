@@ -15,7 +15,12 @@
 package unpack
 
 import (
+	"encoding/binary"
+	"errors"
 	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -23,6 +28,77 @@ import (
 	"github.com/googlecloudplatform/pi-delivery/pkg/ycd"
 )
 
+const zeros = "0000000000000000000"
+
+func copyWithZero(dst []byte, s string, nz int) int {
+	return copy(dst, zeros[:nz]) + copy(dst[nz:], s)
+}
+
+// unpackBlockStrconv is the original implementation of UnpackBlock with strconv.FormatUint.
+// UnpackBlock must behave exactly the same.
+func unpackBlockStrconv(unpacked, packed []byte, radix, pre int) (int, error) {
+	if len(packed) == 0 || len(unpacked) == 0 {
+		return 0, nil
+	}
+
+	dpw := ycd.DigitsPerWord(radix)
+
+	unpackedLen := UnpackedLen(int64(len(packed)-1), radix) - int64(pre)
+	if int64(len(unpacked)) < unpackedLen {
+		return 0, fmt.Errorf("%w: required = %v bytes, actual buffer = %v bytes",
+			ErrBufferTooSmall, unpackedLen, len(unpacked))
+	}
+
+	// Unpack the first word with pre.
+	// Copy dpw-pre bytes.
+	s := strconv.FormatUint(binary.LittleEndian.Uint64(packed), radix)
+	nz := dpw - len(s)
+	if nz < 0 {
+		return 0, fmt.Errorf("%w: word = %16x, unpacked = %s",
+			ErrInvalidWord, packed[:WordSize], s)
+	}
+	nzNeeded := nz - pre
+	if nzNeeded < 0 {
+		nzNeeded = 0
+	}
+	n := copy(unpacked, zeros[:nzNeeded])
+	if n < dpw-pre && n < len(unpacked) {
+		if nz < pre {
+			n += copy(unpacked[n:], s[pre-nz:dpw-pre-n+(pre-nz)])
+		} else {
+			n += copy(unpacked[n:], s[:dpw-pre-n])
+		}
+	}
+
+	if len(packed) == WordSize {
+		return n, nil
+	}
+
+	// Process until the second last word.
+	for i := WordSize; i < len(packed)-WordSize; i += WordSize {
+		s := strconv.FormatUint(binary.LittleEndian.Uint64(packed[i:]), radix)
+		nz := dpw - len(s)
+		if nz < 0 {
+			return n, fmt.Errorf("%w: word = %16x, unpacked = %s", ErrInvalidWord,
+				packed[i:i+WordSize], s)
+		}
+		n += copyWithZero(unpacked[n:], s, nz)
+	}
+
+	// Process the last word with post.
+	s = strconv.FormatUint(binary.LittleEndian.Uint64(packed[len(packed)-WordSize:]), radix)
+	nz = dpw - len(s)
+	if nz < 0 {
+		return n, fmt.Errorf("%w: word = %16x, unpacked = %s", ErrInvalidWord,
+			packed[len(packed)-WordSize:], s)
+	}
+	n += copy(unpacked[n:], zeros[:nz])
+	if n < len(unpacked) {
+		n += copy(unpacked[n:], s)
+	}
+	return n, nil
+}
+
 func TestUnpack_ToPackedOffsets(t *testing.T) {
 	testCases := []struct {
 		radix             int
@@ -102,6 +178,42 @@ func TestUnpack_Errors(t *testing.T) {
 	}
 }
 
+func TestUnpack_ValidWord(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		word  uint64
+		radix int
+		want  bool
+	}{
+		{0, 10, true},
+		{maxDecimalWord - 1, 10, true},
+		{maxDecimalWord, 10, false},
+		{math.MaxUint64, 10, false},
+		{0, 16, true},
+		{math.MaxUint64, 16, true},
+		{0, 8, false},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(fmt.Sprintf("Word %d Radix %d", tc.word, tc.radix), func(t *testing.T) {
+			t.Parallel()
+			if got := ValidWord(tc.word, tc.radix); got != tc.want {
+				t.Errorf("ValidWord() = got %v, want %v", got, tc.want)
+			}
+			if tc.radix != 10 && tc.radix != 16 {
+				return
+			}
+			// ValidWord must agree with UnpackBlock.
+			packed := binary.LittleEndian.AppendUint64(nil, tc.word)
+			unpacked := make([]byte, ycd.DigitsPerWord(tc.radix))
+			_, err := UnpackBlock(unpacked, packed, tc.radix, 0)
+			if valid := !errors.Is(err, ErrInvalidWord); valid != tc.want {
+				t.Errorf("UnpackBlock() = got error %v, want valid = %v", err, tc.want)
+			}
+		})
+	}
+}
+
 func TestUnpack_Unpack(t *testing.T) {
 	t.Parallel()
 
@@ -175,3 +287,104 @@ func TestUnpack_Unpack(t *testing.T) {
 		})
 	}
 }
+
+// randomPacked returns n random words in radix. One in invalidEvery words is
+// an invalid decimal word if invalidEvery > 0.
+func randomPacked(r *rand.Rand, n, radix, invalidEvery int) []byte {
+	packed := make([]byte, 0, n*WordSize)
+	for i := 0; i < n; i++ {
+		w := r.Uint64()
+		if radix == 10 && (invalidEvery <= 0 || r.Intn(invalidEvery) != 0) {
+			w %= maxDecimalWord
+		}
+		packed = binary.LittleEndian.AppendUint64(packed, w)
+	}
+	return packed
+}
+
+// checkSameAsStrconv checks that UnpackBlock returns the same digits, count and error as
+// unpackBlockStrconv for an unpacked buffer of size bufLen.
+func checkSameAsStrconv(t *testing.T, packed []byte, radix, pre, bufLen int) {
+	t.Helper()
+	want := make([]byte, bufLen)
+	got := make([]byte, bufLen)
+	wantN, wantErr := unpackBlockStrconv(want, packed, radix, pre)
+	gotN, gotErr := UnpackBlock(got, packed, radix, pre)
+	if gotN != wantN {
+		t.Errorf("UnpackBlock(%x, %d, %d) with %d bytes: n = got %d, want %d", packed, radix, pre, bufLen, gotN, wantN)
+	}
+	if fmt.Sprint(gotErr) != fmt.Sprint(wantErr) || errors.Is(gotErr, ErrInvalidWord) != errors.Is(wantErr, ErrInvalidWord) {
+		t.Errorf("UnpackBlock(%x, %d, %d) with %d bytes: error = got %v, want %v", packed, radix, pre, bufLen, gotErr, wantErr)
+	}
+	if diff := cmp.Diff(want[:wantN], got[:gotN]); diff != "" {
+		t.Errorf("UnpackBlock(%x, %d, %d) with %d bytes = (-want, +got):\n%s", packed, radix, pre, bufLen, diff)
+	}
+}
+
+func TestUnpack_SameAsStrconv(t *testing.T) {
+	t.Parallel()
+	r := rand.New(rand.NewSource(1))
+	for _, radix := range []int{10, 16} {
+		dpw := ycd.DigitsPerWord(radix)
+		for words := 1; words <= 4; words++ {
+			for iter := 0; iter < 10; iter++ {
+				packed := randomPacked(r, words, radix, 8)
+				for pre := 0; pre < dpw; pre++ {
+					for bufLen := 0; bufLen <= words*dpw-pre; bufLen++ {
+						checkSameAsStrconv(t, packed, radix, pre, bufLen)
+					}
+				}
+			}
+		}
+	}
+	// Edge words.
+	for _, w := range []uint64{0, 1, 9, 10, 99, 100, 1e8 - 1, 1e8, 1e16 - 1, 1e16, maxDecimalWord - 1, maxDecimalWord, math.MaxUint64} {
+		for _, radix := range []int{10, 16} {
+			packed := binary.LittleEndian.AppendUint64(nil, w)
+			packed = binary.LittleEndian.AppendUint64(packed, w)
+			checkSameAsStrconv(t, packed, radix, 0, 2*ycd.DigitsPerWord(radix))
+		}
+	}
+}
+
+func FuzzUnpackBlock(f *testing.F) {
+	f.Add([]byte{0x60, 0xe2, 0x3e, 0xb8, 0xae, 0x61, 0xa6, 0x13}, true, 1, 17)
+	f.Add([]byte{0x7a, 0x13, 0x6c, 0x0b, 0xef, 0x6e, 0x98, 0x2a, 0xfb, 0x7e, 0x50, 0xf0, 0x3b, 0xba, 0x76, 0x01}, false, 3, 20)
+	f.Fuzz(func(t *testing.T, packed []byte, decimal bool, pre, bufLen int) {
+		radix := 16
+		if decimal {
+			radix = 10
+		}
+		dpw := ycd.DigitsPerWord(radix)
+		// Partial words and out of range arguments aren't supported.
+		if len(packed)%WordSize != 0 || pre < 0 || pre >= dpw || bufLen < 0 || bufLen > len(packed)/WordSize*dpw {
+			return
+		}
+		checkSameAsStrconv(t, packed, radix, pre, bufLen)
+	})
+}
+
+func BenchmarkUnpackBlock(b *testing.B) {
+	const words = 64 * 1024
+	for _, radix := range []int{10, 16} {
+		packed := randomPacked(rand.New(rand.NewSource(1)), words, radix, 0)
+		unpacked := make([]byte, UnpackedLen(int64(len(packed)), radix))
+		for _, bc := range []struct {
+			name   string
+			unpack func(unpacked, packed []byte, radix, pre int) (int, error)
+		}{
+			{"Table", UnpackBlock},
+			{"Strconv", unpackBlockStrconv},
+		} {
+			b.Run(fmt.Sprintf("Radix %d %s", radix, bc.name), func(b *testing.B) {
+				b.SetBytes(int64(len(packed)))
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					if _, err := bc.unpack(unpacked, packed, radix, 1); err != nil {
+						b.Fatalf("unpack failed: %v", err)
+					}
+				}
+			})
+		}
+	}
+}
@@ -16,46 +16,93 @@ package ycd
 
 import (
 	"bufio"
+	"encoding/json"
 	"fmt"
+	"io"
+	"sort"
 	"strconv"
 	"strings"
 )
 
 type Header struct {
 	// FileVersion is the version of the ycd file.
-	// Currently it's 1.1.0 and this code is tested against the version.
-	FileVersion string
+	// This code is tested against 1.1.0 and accepts any 1.x.y version
+	// since they share the layout.
+	FileVersion string `json:"fileVersion"`
 
 	// Radis is the radix of the file. 10 or 16.
-	Radix int
+	Radix int `json:"radix"`
 
 	// FirstDigits is always the first several digits of pi?
 	// e.g. 3.14159265358979323846264338327950288419716939937510 for decimal and
 	// 3.243f6a8885a308d313198a2e03707344a4093822299f31d008 for hexadecimal.
-	FirstDigits string
+	FirstDigits string `json:"firstDigits"`
 
 	// TotalDigits is zero if the file has n == BlockSize.
 	// otherwise it's the number of digits in the file.
-	TotalDigits int64
+	TotalDigits int64 `json:"totalDigits"`
 
 	// BlockSize is digits per file.
-	BlockSize int64
+	BlockSize int64 `json:"blockSize"`
 
 	// BlockID is the position of the current file.
-	BlockID int64
+	BlockID int64 `json:"blockID"`
 
 	// Length is the total byte length of the header in the file.
 	// It is the offset of the empty line after EndHeader.
-	Length int
+	Length int `json:"length"`
+
+	// Extra has the values of the keys this package doesn't know,
+	// e.g. ones added by newer versions of y-cruncher.
+	Extra map[string]string `json:"extra,omitempty"`
+}
+
+// SupportedMajorVersion is the major file version this package can read.
+const SupportedMajorVersion = 1
+
+// Header keys.
+const (
+	keyFileVersion = "FileVersion"
+	keyBase        = "Base"
+	keyFirstDigits = "FirstDigits"
+	keyTotalDigits = "TotalDigits"
+	keyBlockSize   = "Blocksize"
+	keyBlockID     = "BlockID"
+	keyEndHeader   = "EndHeader"
+)
+
+func isKnownKey(key string) bool {
+	switch key {
+	case keyFileVersion, keyBase, keyFirstDigits, keyTotalDigits, keyBlockSize, keyBlockID, keyEndHeader:
+		return true
+	}
+	return false
 }
 
 func parseInt64(s string) (int64, error) {
 	return strconv.ParseInt(s, 10, 64)
 }
 
+// checkFileVersion returns an error unless v is "major.minor.patch" with SupportedMajorVersion.
+func checkFileVersion(v string) error {
+	parts := strings.Split(v, ".")
+	if len(parts) != 3 {
+		return fmt.Errorf("invalid file version: %s", v)
+	}
+	for _, p := range parts {
+		if _, err := strconv.ParseUint(p, 10, 32); err != nil {
+			return fmt.Errorf("invalid file version: %s", v)
+		}
+	}
+	if parts[0] != strconv.Itoa(SupportedMajorVersion) {
+		return fmt.Errorf("unsupported file version: %s", v)
+	}
+	return nil
+}
+
 func (h *Header) validate() error {
-	if h.FileVersion != "1.1.0" {
-		return fmt.Errorf("unknown file version: %s", h.FileVersion)
+	if err := checkFileVersion(h.FileVersion); err != nil {
+		return err
 	}
 	if h.Radix != 10 && h.Radix != 16 {
 		return fmt.Errorf("unknown radix: %v", h.Radix)
@@ -63,6 +110,84 @@ func (h *Header) validate() error {
 	return nil
 }
 
+// MarshalText returns the header lines of h in the layout y-cruncher writes,
+// from "#Compressed Digit File" to "EndHeader" with CRLF line endings.
+// The result is h.Length bytes long for a header parsed from a y-cruncher file.
+// The empty line and the NUL that precede the digits in a file aren't included.
+// It fails if h doesn't validate or a value can't be parsed back as it is.
+func (h *Header) MarshalText() ([]byte, error) {
+	if err := h.validate(); err != nil {
+		return nil, err
+	}
+	if err := checkValue(h.FirstDigits); err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(h.Extra))
+	for k, v := range h.Extra {
+		if err := checkKey(k); err != nil {
+			return nil, err
+		}
+		if err := checkValue(v); err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	b := make([]byte, 0, 256)
+	b = append(b, "#Compressed Digit File\r\n\r\n"...)
+	b = append(b, "FileVersion:\t"+h.FileVersion+"\r\n\r\n"...)
+	b = append(b, "Base:\t"+strconv.Itoa(h.Radix)+"\r\n\r\n"...)
+	b = append(b, "FirstDigits:\t"+h.FirstDigits+"\r\n\r\n"...)
+	b = append(b, "TotalDigits:\t"+strconv.FormatInt(h.TotalDigits, 10)+"\r\n\r\n"...)
+	b = append(b, "Blocksize:\t"+strconv.FormatInt(h.BlockSize, 10)+"\r\n"...)
+	b = append(b, "BlockID:\t"+strconv.FormatInt(h.BlockID, 10)+"\r\n\r\n"...)
+	// Unknown keys follow the known ones in lexical order.
+	for _, k := range keys {
+		b = append(b, k+":\t"+h.Extra[k]+"\r\n\r\n"...)
+	}
+	b = append(b, "EndHeader\r\n"...)
+	return b, nil
+}
+
+// WriteTo writes the header lines of h as MarshalText returns them to w.
+func (h *Header) WriteTo(w io.Writer) (int64, error) {
+	b, err := h.MarshalText()
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(b)
+	return int64(n), err
+}
+
+// MarshalJSON encodes h as a JSON object of its fields.
+// Without it, encoding/json would use the header text from MarshalText.
+func (h *Header) MarshalJSON() ([]byte, error) {
+	type header Header
+	return json.Marshal((*header)(h))
+}
+
+// checkValue returns an error if v doesn't survive parsing as a header value.
+func checkValue(v string) error {
+	if strings.TrimSpace(v) != v {
+		return fmt.Errorf("header value has leading or trailing spaces: %q", v)
+	}
+	if strings.ContainsAny(v, "\r\n") {
+		return fmt.Errorf("header value has a line break: %q", v)
+	}
+	return nil
+}
+
+// checkKey returns an error if k can't be an unknown header key.
+func checkKey(k string) error {
+	if k == "" || strings.TrimSpace(k) != k || strings.ContainsAny(k, ":\r\n") {
+		return fmt.Errorf("invalid header key: %q", k)
+	}
+	if isKnownKey(k) || strings.HasPrefix(k, "#") {
+		return fmt.Errorf("reserved header key: %q", k)
+	}
+	return nil
+}
+
 func parseHeader(reader *bufio.Reader) (*Header, error) {
 	var h Header
 	length := 0
@@ -86,44 +211,51 @@ func parseHeader(reader *bufio.Reader) (*Header, error) {
 		if len(line) == 2 {
 			continue
 		}
-		tokens := strings.Split(line, ":")
-		key := strings.TrimSpace(tokens[0])
-		if key == "EndHeader" {
+		// Values may contain colons, e.g. timestamps, so only the first one separates the key.
+		key, value, ok := strings.Cut(line, ":")
+		key = strings.TrimSpace(key)
+		if key == keyEndHeader {
 			break
 		}
+		if !ok {
+			return nil, fmt.Errorf("malformed header line: %s", strings.TrimSpace(line))
+		}
 
-		value := strings.TrimSpace(tokens[1])
+		value = strings.TrimSpace(value)
 		switch key {
-		case "FileVersion":
+		case keyFileVersion:
 			h.FileVersion = value
-		case "Base":
+		case keyBase:
 			if i, err := strconv.Atoi(value); err == nil {
 				h.Radix = i
 			} else {
 				return nil, err
 			}
-		case "FirstDigits":
+		case keyFirstDigits:
 			h.FirstDigits = value
-		case "TotalDigits":
+		case keyTotalDigits:
 			if i, err := parseInt64(value); err == nil {
 				h.TotalDigits = i
 			} else {
 				return nil, err
 			}
-		case "Blocksize":
+		case keyBlockSize:
 			if i, err := parseInt64(value); err == nil {
 				h.BlockSize = i
 			} else {
 				return nil, err
 			}
-		case "BlockID":
+		case keyBlockID:
 			if i, err := parseInt64(value); err == nil {
 				h.BlockID = i
 			} else {
 				return nil, err
 			}
 		default:
-			return nil, fmt.Errorf("unknown header key: %s", key)
+			if h.Extra == nil {
+				h.Extra = make(map[string]string)
+			}
+			h.Extra[key] = value
 		}
 	}
 	if err := h.validate(); err != nil {
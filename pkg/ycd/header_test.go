@@ -0,0 +1,242 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ycd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestHeader_MarshalText(t *testing.T) {
+	t.Parallel()
+
+	for _, raw := range []string{rawTestDataHex, rawTestDataDec} {
+		raw := strings.ReplaceAll(raw, "\n", "\r\n")
+		f, err := Parse(strings.NewReader(raw + "\x00"))
+		if err != nil {
+			t.Fatalf("Parse() failed: %v", err)
+		}
+		got, err := f.Header.MarshalText()
+		if err != nil {
+			t.Fatalf("MarshalText() failed: %v", err)
+		}
+		// The raw data ends with the empty line before the NUL.
+		if diff := cmp.Diff(strings.TrimSuffix(raw, "\r\n"), string(got)); diff != "" {
+			t.Errorf("MarshalText() = (-want, +got):\n%s", diff)
+		}
+		if len(got) != f.Header.Length {
+			t.Errorf("MarshalText() length = got %d, want %d", len(got), f.Header.Length)
+		}
+
+		var buf bytes.Buffer
+		n, err := f.Header.WriteTo(&buf)
+		if err != nil {
+			t.Fatalf("WriteTo() failed: %v", err)
+		}
+		if n != int64(len(got)) || !bytes.Equal(buf.Bytes(), got) {
+			t.Errorf("WriteTo() = got %d bytes %q, want %q", n, buf.Bytes(), got)
+		}
+	}
+}
+
+func TestHeader_MarshalTextErrors(t *testing.T) {
+	t.Parallel()
+
+	valid := Header{FileVersion: "1.1.0", Radix: 10, FirstDigits: "3.14", BlockSize: 100}
+	testCases := []struct {
+		name   string
+		modify func(h *Header)
+	}{
+		{"version", func(h *Header) { h.FileVersion = "" }},
+		{"major version", func(h *Header) { h.FileVersion = "2.0.0" }},
+		{"radix", func(h *Header) { h.Radix = 2 }},
+		{"spaces", func(h *Header) { h.FirstDigits = " 3.14" }},
+		{"line break", func(h *Header) { h.FirstDigits = "3.14\r\nBlockID:\t5" }},
+		{"extra known key", func(h *Header) { h.Extra = map[string]string{"Base": "16"} }},
+		{"extra empty key", func(h *Header) { h.Extra = map[string]string{"": "x"} }},
+		{"extra key with colon", func(h *Header) { h.Extra = map[string]string{"a:b": "x"} }},
+		{"extra value with line break", func(h *Header) { h.Extra = map[string]string{"Date": "a\nb"} }},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			h := valid
+			tc.modify(&h)
+			if _, err := h.MarshalText(); err == nil {
+				t.Errorf("MarshalText() should fail for %+v", h)
+			}
+			if _, err := h.WriteTo(&bytes.Buffer{}); err == nil {
+				t.Errorf("WriteTo() should fail for %+v", h)
+			}
+		})
+	}
+}
+
+func TestHeader_MarshalJSON(t *testing.T) {
+	t.Parallel()
+
+	h := &Header{FileVersion: "1.1.0", Radix: 10, FirstDigits: "3.14", TotalDigits: 5, BlockSize: 100, BlockID: 2, Length: 150}
+	b, err := json.Marshal(h)
+	if err != nil {
+		t.Fatalf("json.Marshal() failed: %v", err)
+	}
+	want := `{"fileVersion":"1.1.0","radix":10,"firstDigits":"3.14","totalDigits":5,"blockSize":100,"blockID":2,"length":150}`
+	if diff := cmp.Diff(want, string(b)); diff != "" {
+		t.Errorf("json.Marshal() = (-want, +got):\n%s", diff)
+	}
+	got := new(Header)
+	if err := json.Unmarshal(b, got); err != nil {
+		t.Fatalf("json.Unmarshal() failed: %v", err)
+	}
+	if diff := cmp.Diff(h, got); diff != "" {
+		t.Errorf("json.Unmarshal() = (-want, +got):\n%s", diff)
+	}
+}
+
+// roundTrip parses the header h marshals to as a YCD file.
+func roundTrip(h *Header) (*Header, error) {
+	b, err := h.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	f, err := Parse(bytes.NewReader(append(b, headerTrailer...)))
+	if err != nil {
+		return nil, err
+	}
+	return f.Header, nil
+}
+
+func TestHeader_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	testCases := []*Header{
+		{FileVersion: "1.1.0", Radix: 10, FirstDigits: "3.14159265358979323846264338327950288419716939937510",
+			TotalDigits: 0, BlockSize: 100000000000, BlockID: 0},
+		{FileVersion: "1.1.0", Radix: 16, FirstDigits: "3.243f6a8885a308d313198a2e03707344a4093822299f31d008",
+			TotalDigits: 83048202372185, BlockSize: 100000000000, BlockID: 830},
+		{FileVersion: "1.1.0", Radix: 10, FirstDigits: "", BlockSize: 1},
+		{FileVersion: "1.0.0", Radix: 16, FirstDigits: "3.243f", BlockSize: 10,
+			Extra: map[string]string{"Timestamp": "2023-03-14 12:34:56", "Zeta": "", "Alpha": "a: b"}},
+	}
+	for _, h := range testCases {
+		got, err := roundTrip(h)
+		if err != nil {
+			t.Fatalf("round trip failed for %+v: %v", h, err)
+		}
+		want := *h
+		b, _ := h.MarshalText()
+		want.Length = len(b)
+		if diff := cmp.Diff(&want, got); diff != "" {
+			t.Errorf("Parse(MarshalText()) = (-want, +got):\n%s", diff)
+		}
+	}
+}
+
+func FuzzHeader_RoundTrip(f *testing.F) {
+	f.Add(true, "3.14159265358979323846264338327950288419716939937510", int64(0), int64(100000000000), int64(0), "", "")
+	f.Add(false, "3.243f6a8885a308d313198a2e03707344a4093822299f31d008", int64(83048202372185), int64(100000000000), int64(830), "", "")
+	f.Add(true, "", int64(-1), int64(0), int64(-5), "Timestamp", "12:34:56")
+	f.Fuzz(func(t *testing.T, decimal bool, firstDigits string, total, blockSize, blockID int64, key, value string) {
+		h := &Header{
+			FileVersion: "1.1.0",
+			Radix:       16,
+			FirstDigits: firstDigits,
+			TotalDigits: total,
+			BlockSize:   blockSize,
+			BlockID:     blockID,
+		}
+		if decimal {
+			h.Radix = 10
+		}
+		if key != "" {
+			h.Extra = map[string]string{key: value}
+		}
+		b, err := h.MarshalText()
+		if err != nil {
+			// Only keys and values that can't be parsed back may be rejected.
+			if checkValue(firstDigits) == nil && (key == "" || checkKey(key) == nil && checkValue(value) == nil) {
+				t.Fatalf("MarshalText() failed for %+v: %v", h, err)
+			}
+			return
+		}
+		got, err := roundTrip(h)
+		if err != nil {
+			t.Fatalf("Parse() failed for %q: %v", b, err)
+		}
+		h.Length = len(b)
+		if diff := cmp.Diff(h, got); diff != "" {
+			t.Errorf("Parse(MarshalText()) = (-want, +got):\n%s", diff)
+		}
+	})
+}
+
+func TestHeader_ParseTolerant(t *testing.T) {
+	t.Parallel()
+
+	const base = "#Compressed Digit File\n\nFileVersion:\t%s\n\nBase:\t10\n\nFirstDigits:\t3.14\n\n" +
+		"TotalDigits:\t0\n\nBlocksize:\t100\nBlockID:\t0\n\n%sEndHeader\n\n"
+	testCases := []struct {
+		name      string
+		version   string
+		extra     string
+		want      map[string]string
+		wantError bool
+	}{
+		{name: "1.1.0", version: "1.1.0"},
+		{name: "1.0.0", version: "1.0.0"},
+		{name: "1.2.3", version: "1.2.3"},
+		{name: "unknown keys", version: "1.1.0",
+			extra: "Timestamp:\tSat Mar 14 01:59:26 2026\n\nComment:\t\n\n",
+			want:  map[string]string{"Timestamp": "Sat Mar 14 01:59:26 2026", "Comment": ""}},
+		{name: "value with colons", version: "1.1.0",
+			extra: "URL:\thttp://example.com:8080/\n\n",
+			want:  map[string]string{"URL": "http://example.com:8080/"}},
+		{name: "major version", version: "2.0.0", wantError: true},
+		{name: "malformed version", version: "1.1", wantError: true},
+		{name: "malformed line", version: "1.1.0", extra: "NoColon\n", wantError: true},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			raw := strings.ReplaceAll(fmt.Sprintf(base, tc.version, tc.extra), "\n", "\r\n") + "\x00"
+			f, err := Parse(strings.NewReader(raw))
+			if tc.wantError {
+				if err == nil {
+					t.Errorf("Parse() should fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse() failed: %v", err)
+			}
+			if f.Header.FileVersion != tc.version {
+				t.Errorf("FileVersion = got %s, want %s", f.Header.FileVersion, tc.version)
+			}
+			if diff := cmp.Diff(tc.want, f.Header.Extra); diff != "" {
+				t.Errorf("Extra = (-want, +got):\n%s", diff)
+			}
+			if f.FirstDigitOffset != len(raw) {
+				t.Errorf("FirstDigitOffset = got %d, want %d", f.FirstDigitOffset, len(raw))
+			}
+		})
+	}
+}
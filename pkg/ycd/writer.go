@@ -0,0 +1,180 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ycd
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// DefaultFileVersion is the file version Writer writes if the header doesn't have one.
+const DefaultFileVersion = "1.1.0"
+
+// ErrInvalidDigit is returned when Writer gets a byte that isn't a digit in the radix.
+var ErrInvalidDigit = errors.New("ycd: invalid digit")
+
+// ErrBlockFull is returned when Writer gets more digits than the block holds.
+var ErrBlockFull = errors.New("ycd: too many digits for the block")
+
+// ErrShortBlock is returned when Writer is closed before it gets all the digits of the block.
+var ErrShortBlock = errors.New("ycd: not enough digits for the block")
+
+// headerTrailer follows the header lines. The digits start after the NUL.
+const headerTrailer = "\r\n\x00"
+
+// Writer writes a block of ASCII digits as a YCD file.
+// Digits are the ones after the radix point, starting at BlockID * BlockSize.
+type Writer struct {
+	w     io.Writer
+	radix uint64
+	dpw   int
+	// want is the number of digits in the block.
+	want int64
+	// n is the number of digits written so far.
+	n int64
+	// word is the current word and nw is the number of digits in it.
+	word uint64
+	nw   int
+	buf  []byte
+	err  error
+}
+
+// NewWriter writes the header of a YCD file for h to w and returns a Writer
+// for its digits. h.FileVersion defaults to DefaultFileVersion and h.Length is ignored.
+// The block holds h.BlockSize digits, or if h.TotalDigits is set,
+// the digits up to TotalDigits if it ends in the block.
+func NewWriter(w io.Writer, h *Header) (*Writer, error) {
+	hh := *h
+	if hh.FileVersion == "" {
+		hh.FileVersion = DefaultFileVersion
+	}
+	if hh.BlockSize <= 0 {
+		return nil, fmt.Errorf("invalid block size: %d", hh.BlockSize)
+	}
+	if hh.BlockID < 0 {
+		return nil, fmt.Errorf("invalid block ID: %d", hh.BlockID)
+	}
+	want := hh.BlockSize
+	if hh.TotalDigits != 0 {
+		remaining := hh.TotalDigits - hh.BlockID*hh.BlockSize
+		if remaining <= 0 {
+			return nil, fmt.Errorf("block %d starts after the total digits %d", hh.BlockID, hh.TotalDigits)
+		}
+		if remaining < want {
+			want = remaining
+		}
+	}
+
+	b, err := hh.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	b = append(b, headerTrailer...)
+	if _, err := w.Write(b); err != nil {
+		return nil, err
+	}
+	return &Writer{
+		w:     w,
+		radix: uint64(hh.Radix),
+		dpw:   DigitsPerWord(hh.Radix),
+		want:  want,
+		buf:   make([]byte, 0, 4096),
+	}, nil
+}
+
+func digitValue(c byte) uint64 {
+	switch {
+	case '0' <= c && c <= '9':
+		return uint64(c - '0')
+	case 'a' <= c && c <= 'f':
+		return uint64(c-'a') + 10
+	case 'A' <= c && c <= 'F':
+		return uint64(c-'A') + 10
+	default:
+		return 255
+	}
+}
+
+// Write packs the ASCII digits in p.
+// It fails with ErrInvalidDigit or ErrBlockFull without writing any of p.
+func (w *Writer) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	if w.n+int64(len(p)) > w.want {
+		return 0, fmt.Errorf("%w: %d digits", ErrBlockFull, w.want)
+	}
+	for i, c := range p {
+		if digitValue(c) >= w.radix {
+			return 0, fmt.Errorf("%w: %q at %d", ErrInvalidDigit, c, w.n+int64(i))
+		}
+	}
+	for _, c := range p {
+		w.word = w.word*w.radix + digitValue(c)
+		w.nw++
+		if w.nw == w.dpw {
+			if err := w.appendWord(); err != nil {
+				return 0, err
+			}
+		}
+	}
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+// appendWord buffers the current word and flushes the buffer when it's full.
+func (w *Writer) appendWord() error {
+	w.buf = binary.LittleEndian.AppendUint64(w.buf, w.word)
+	w.word, w.nw = 0, 0
+	if len(w.buf) == cap(w.buf) {
+		return w.flush()
+	}
+	return nil
+}
+
+func (w *Writer) flush() error {
+	if _, err := w.w.Write(w.buf); err != nil {
+		w.err = err
+		return err
+	}
+	w.buf = w.buf[:0]
+	return nil
+}
+
+// Close pads the last word with zeros and flushes the digits.
+// It returns ErrShortBlock if the block doesn't have all of its digits.
+// It doesn't close the underlying io.Writer.
+func (w *Writer) Close() error {
+	if w.err != nil {
+		return w.err
+	}
+	if w.n != w.want {
+		w.err = fmt.Errorf("%w: got %d digits, want %d", ErrShortBlock, w.n, w.want)
+		return w.err
+	}
+	if w.nw > 0 {
+		for ; w.nw < w.dpw; w.nw++ {
+			w.word *= w.radix
+		}
+		w.buf = binary.LittleEndian.AppendUint64(w.buf, w.word)
+	}
+	if err := w.flush(); err != nil {
+		return err
+	}
+	w.err = errors.New("ycd: writer closed")
+	return nil
+}
@@ -0,0 +1,239 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ycd
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWriter_ByteExact(t *testing.T) {
+	t.Parallel()
+
+	hexDigits := strings.Repeat("0123456789abcdef", 1000000/16)
+	hexWords := bytes.Repeat(binary.LittleEndian.AppendUint64(nil, 0x0123456789abcdef), 1000000/16)
+	testCases := []struct {
+		name   string
+		raw    string
+		header *Header
+		digits string
+		words  []byte
+	}{
+		{
+			name: "hex",
+			raw:  rawTestDataHex,
+			header: &Header{
+				Radix:       16,
+				FirstDigits: "3.243f6a8885a308d313198a2e03707344a4093822299f31d008",
+				BlockSize:   1000000,
+			},
+			digits: hexDigits,
+			words:  hexWords,
+		},
+		{
+			name: "dec",
+			raw:  rawTestDataDec,
+			header: &Header{
+				FileVersion: "1.1.0",
+				Radix:       10,
+				FirstDigits: "3.14159265358979323846264338327950288419716939937510",
+				TotalDigits: 50000001,
+				BlockSize:   1000000,
+				BlockID:     50,
+			},
+			// The last block has a single digit padded with zeros.
+			digits: "7",
+			words:  binary.LittleEndian.AppendUint64(nil, 7_000_000_000_000_000_000),
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			var buf bytes.Buffer
+			w, err := NewWriter(&buf, tc.header)
+			if err != nil {
+				t.Fatalf("NewWriter() failed: %v", err)
+			}
+			if _, err := w.Write([]byte(tc.digits)); err != nil {
+				t.Fatalf("Write() failed: %v", err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("Close() failed: %v", err)
+			}
+			want := append([]byte(strings.ReplaceAll(tc.raw, "\n", "\r\n")+"\x00"), tc.words...)
+			if !bytes.Equal(want, buf.Bytes()) {
+				t.Errorf("Writer wrote %q..., want %q...", buf.Bytes()[:256], want[:256])
+			}
+		})
+	}
+}
+
+func TestWriter_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		radix     int
+		blockSize int64
+		total     int64
+		blockID   int64
+	}{
+		{10, 40, 0, 0},
+		{10, 38, 0, 3},
+		{10, 40, 100, 2},
+		{16, 40, 0, 1},
+		{16, 32, 50, 1},
+		{16, 1, 0, 7},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(fmt.Sprintf("radix %d block size %d total %d id %d", tc.radix, tc.blockSize, tc.total, tc.blockID), func(t *testing.T) {
+			t.Parallel()
+			h := &Header{
+				Radix:       tc.radix,
+				FirstDigits: "3.14",
+				TotalDigits: tc.total,
+				BlockSize:   tc.blockSize,
+				BlockID:     tc.blockID,
+			}
+			n := tc.blockSize
+			if tc.total != 0 && tc.total-tc.blockID*tc.blockSize < n {
+				n = tc.total - tc.blockID*tc.blockSize
+			}
+			r := rand.New(rand.NewSource(n))
+			digits := make([]byte, n)
+			for i := range digits {
+				digits[i] = strconv.FormatInt(int64(r.Intn(tc.radix)), tc.radix)[0]
+			}
+
+			var buf bytes.Buffer
+			w, err := NewWriter(&buf, h)
+			if err != nil {
+				t.Fatalf("NewWriter() failed: %v", err)
+			}
+			// Write in uneven pieces to cross word boundaries.
+			for i := int64(0); i < n; i += 7 {
+				end := i + 7
+				if end > n {
+					end = n
+				}
+				if _, err := w.Write(digits[i:end]); err != nil {
+					t.Fatalf("Write() failed: %v", err)
+				}
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("Close() failed: %v", err)
+			}
+
+			f, err := Parse(bytes.NewReader(buf.Bytes()))
+			if err != nil {
+				t.Fatalf("Parse() failed: %v", err)
+			}
+			h.FileVersion = DefaultFileVersion
+			h.Length = f.Header.Length
+			if diff := cmp.Diff(h, f.Header); diff != "" {
+				t.Errorf("Parse() header = (-want, +got):\n%s", diff)
+			}
+			dpw := DigitsPerWord(tc.radix)
+			packed := buf.Bytes()[f.FirstDigitOffset:]
+			if want := (n + int64(dpw) - 1) / int64(dpw) * WordSize; int64(len(packed)) != want {
+				t.Fatalf("packed length = got %d, want %d", len(packed), want)
+			}
+			var got []byte
+			for i := 0; i < len(packed); i += WordSize {
+				s := strconv.FormatUint(binary.LittleEndian.Uint64(packed[i:]), tc.radix)
+				got = append(got, strings.Repeat("0", dpw-len(s))+s...)
+			}
+			if diff := cmp.Diff(string(digits), string(got[:n])); diff != "" {
+				t.Errorf("unpacked digits = (-want, +got):\n%s", diff)
+			}
+			if pad := strings.Trim(string(got[n:]), "0"); pad != "" {
+				t.Errorf("padding = got %q, want zeros", got[n:])
+			}
+		})
+	}
+}
+
+func TestWriter_Errors(t *testing.T) {
+	t.Parallel()
+
+	header := &Header{Radix: 10, BlockSize: 20}
+	if _, err := NewWriter(&bytes.Buffer{}, &Header{Radix: 8, BlockSize: 20}); err == nil {
+		t.Errorf("NewWriter() should fail for radix 8")
+	}
+	if _, err := NewWriter(&bytes.Buffer{}, &Header{Radix: 10}); err == nil {
+		t.Errorf("NewWriter() should fail for block size 0")
+	}
+	if _, err := NewWriter(&bytes.Buffer{}, &Header{Radix: 10, BlockSize: 20, BlockID: 5, TotalDigits: 100}); err == nil {
+		t.Errorf("NewWriter() should fail for a block after the total digits")
+	}
+
+	testCases := []struct {
+		name    string
+		digits  string
+		wantErr error
+		// closeErr is the error expected from Close after a successful Write.
+		closeErr error
+	}{
+		{"invalid digit", "12a", ErrInvalidDigit, nil},
+		{"block full", strings.Repeat("1", 21), ErrBlockFull, nil},
+		{"short block", strings.Repeat("1", 19), nil, ErrShortBlock},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			w, err := NewWriter(&bytes.Buffer{}, header)
+			if err != nil {
+				t.Fatalf("NewWriter() failed: %v", err)
+			}
+			n, err := w.Write([]byte(tc.digits))
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("Write() error = got %v, want %v", err, tc.wantErr)
+			}
+			if err != nil {
+				if n != 0 {
+					t.Errorf("Write() = got %d, want 0", n)
+				}
+				return
+			}
+			if err := w.Close(); !errors.Is(err, tc.closeErr) {
+				t.Errorf("Close() error = got %v, want %v", err, tc.closeErr)
+			}
+		})
+	}
+
+	w, err := NewWriter(&bytes.Buffer{}, header)
+	if err != nil {
+		t.Fatalf("NewWriter() failed: %v", err)
+	}
+	if _, err := w.Write([]byte(strings.Repeat("9", 20))); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+	if _, err := w.Write([]byte("1")); err == nil {
+		t.Errorf("Write() after Close() should fail")
+	}
+}
@@ -20,9 +20,9 @@ import (
 )
 
 type YCDFile struct {
-	Header           *Header
-	Name             string
-	FirstDigitOffset int
+	Header           *Header `json:"header"`
+	Name             string  `json:"name"`
+	FirstDigitOffset int     `json:"firstDigitOffset"`
 }
 
 // WordSize is the size of a word (64 bits / 8 bytes).